@@ -0,0 +1,577 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"investment-tracker/internal/database"
+	"investment-tracker/internal/instruments"
+	"investment-tracker/internal/market"
+	"investment-tracker/internal/stocks"
+	"investment-tracker/internal/valuation"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load environment variables from .env file
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: Could not load .env file: %v", err)
+	}
+
+	noCache := flag.Bool("no-cache", false, "ignore cached prices and fetch everything live")
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) < 1 {
+		fmt.Println("Usage:")
+		fmt.Println("  go run ./cmd/database [--no-cache] save - Save current portfolio to database")
+		fmt.Println("  go run ./cmd/database stats         - Show portfolio statistics")
+		fmt.Println("  go run ./cmd/database recent <days> - Show recent portfolio history")
+		fmt.Println("  go run ./cmd/database today         - Show today's portfolio data")
+		fmt.Println("  go run ./cmd/database position <ticker|isin|name> [days] - Show a Trading212 position over time")
+		fmt.Println("  go run ./cmd/database instrument [--refresh] <ticker|isin|name> - Look up Trading212 instruments")
+		fmt.Println("  go run ./cmd/database market <ticker|isin|name> - Show whether an instrument's market is open")
+		fmt.Println("  go run ./cmd/database cleanup <days> - Delete data older than N days")
+		fmt.Println("  go run ./cmd/database migrate-currency <code> - Convert stored snapshots to a currency")
+		fmt.Println("  go run ./cmd/database backfill --from YYYY-MM-DD --to YYYY-MM-DD - Rebuild missed days")
+		fmt.Println("  go run ./cmd/database export-trading212 --from YYYY-MM-DD [--to YYYY-MM-DD] [--account name] [--out file.csv] - Import Trading212 history")
+		os.Exit(1)
+	}
+
+	// Exporting to a file and instrument lookups need no database connection
+	switch args[0] {
+	case "export-trading212":
+		exportTrading212(args[1:])
+		return
+	case "instrument":
+		lookupInstrument(args[1:])
+		return
+	case "market":
+		if len(args) < 2 {
+			fmt.Println("Usage: go run ./cmd/database market <ticker|isin|name>")
+			os.Exit(1)
+		}
+		showMarketHours(strings.Join(args[1:], " "))
+		return
+	}
+
+	// Connect to database
+	db, err := database.NewMongoDB()
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	portfolioService := database.NewPortfolioService(db)
+	holdingsService := database.NewHoldingsService(db)
+	command := args[0]
+
+	switch command {
+	case "save":
+		err := saveCurrentPortfolio(portfolioService, holdingsService, *noCache)
+		if err != nil {
+			log.Fatal("Failed to save portfolio:", err)
+		}
+		fmt.Println("Portfolio saved successfully!")
+
+	case "stats":
+		showPortfolioStats(portfolioService)
+
+	case "recent":
+		days := 7 // Default to 7 days
+		if len(args) > 1 {
+			if d, err := strconv.Atoi(args[1]); err == nil {
+				days = d
+			}
+		}
+		showRecentHistory(portfolioService, days)
+
+	case "today":
+		showTodayData(portfolioService)
+
+	case "position":
+		if len(args) < 2 {
+			fmt.Println("Usage: go run ./cmd/database position <ticker|isin|name> [days]")
+			fmt.Println("Example: go run ./cmd/database position AAPL_US_EQ 30")
+			os.Exit(1)
+		}
+		days := 30
+		if len(args) > 2 {
+			if d, err := strconv.Atoi(args[2]); err == nil {
+				days = d
+			}
+		}
+		ticker := args[1]
+		if instrument, err := openCatalogue(false).Resolve(ticker); err == nil {
+			ticker = instrument.Ticker
+		} else {
+			log.Printf("Info: Using %s as a ticker: %v", ticker, err)
+		}
+		showPositionHistory(portfolioService, ticker, days)
+
+	case "cleanup":
+		if len(args) < 2 {
+			fmt.Println("Usage: go run ./cmd/database cleanup <days_to_keep>")
+			os.Exit(1)
+		}
+		days, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatal("Invalid number of days:", args[1])
+		}
+		cleanupOldData(portfolioService, days)
+
+	case "migrate-currency":
+		if len(args) < 2 {
+			fmt.Println("Usage: go run ./cmd/database migrate-currency <currency_code>")
+			fmt.Println("Example: go run ./cmd/database migrate-currency EUR")
+			os.Exit(1)
+		}
+		migrateCurrency(portfolioService, strings.ToUpper(args[1]))
+
+	case "backfill":
+		backfillFlags := flag.NewFlagSet("backfill", flag.ExitOnError)
+		from := backfillFlags.String("from", "", "first day to rebuild (YYYY-MM-DD)")
+		to := backfillFlags.String("to", "", "last day to rebuild (YYYY-MM-DD), defaults to yesterday")
+		backfillFlags.Parse(args[1:])
+
+		if *from == "" {
+			fmt.Println("Usage: go run ./cmd/database backfill --from YYYY-MM-DD [--to YYYY-MM-DD]")
+			os.Exit(1)
+		}
+		backfill(portfolioService, holdingsService, *from, *to)
+
+	default:
+		fmt.Printf("Unknown command: %s\n", command)
+		os.Exit(1)
+	}
+}
+
+func saveCurrentPortfolio(service *database.PortfolioService, holdings *database.HoldingsService, noCache bool) error {
+	engine, err := valuation.NewEngine(valuation.DefaultHoldingsPath)
+	if err != nil {
+		return fmt.Errorf("failed to set up valuation: %w", err)
+	}
+	if noCache {
+		engine.ForceRefresh()
+	}
+	engine.SetPriceHistory(service)
+	engine.SetHoldingsStore(holdings)
+
+	v, err := engine.Value(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get portfolio values: %w", err)
+	}
+
+	return service.SaveDailySnapshot(v)
+}
+
+func showPortfolioStats(service *database.PortfolioService) {
+	stats, err := service.GetPortfolioStats()
+	if err != nil {
+		log.Fatal("Failed to get portfolio stats:", err)
+	}
+
+	fmt.Printf("📊 Portfolio Statistics\n")
+	fmt.Printf("═════════════════════════\n")
+	fmt.Printf("Days tracked: %d\n", stats.DaysTracked)
+	fmt.Printf("Average value: %.2f %s\n", stats.AverageValue, stats.Currency)
+	fmt.Printf("Total growth: %.2f %s (%.2f%%)\n", stats.TotalGrowth, stats.Currency, stats.GrowthPercentage)
+	fmt.Printf("Best day: %s (%.2f %s)\n", stats.BestDay.Format("2006-01-02"), stats.BestDayValue, stats.Currency)
+	fmt.Printf("Worst day: %s (%.2f %s)\n", stats.WorstDay.Format("2006-01-02"), stats.WorstDayValue, stats.Currency)
+}
+
+func showRecentHistory(service *database.PortfolioService, days int) {
+	snapshots, err := service.GetLastNDays(days)
+	if err != nil {
+		log.Fatal("Failed to get recent history:", err)
+	}
+
+	fmt.Printf("📈 Portfolio History (Last %d days)\n", days)
+	fmt.Printf("═══════════════════════════════════════════════════════════════\n")
+	fmt.Printf("%-12s %-12s %-12s %-12s %-12s %-8s\n", "Date", "Trading212", "Crypto", "Bullion", "Total", "Currency")
+	fmt.Printf("───────────────────────────────────────────────────────────────\n")
+
+	incomplete, stale, reconstructed := false, false, false
+	for _, snapshot := range snapshots {
+		marker := ""
+		if snapshot.Incomplete && (len(snapshot.MissingSources) > 0 || len(snapshot.StaleSources) == 0) {
+			marker += " *"
+			incomplete = true
+		}
+		if len(snapshot.StaleSources) > 0 {
+			marker += " S"
+			stale = true
+		}
+		if snapshot.Reconstructed {
+			marker += " R"
+			reconstructed = true
+		}
+		fmt.Printf("%-12s %-12.2f %-12.2f %-12.2f %-12.2f %-8s%s\n",
+			snapshot.Date.Format("2006-01-02"),
+			snapshot.Trading212,
+			snapshot.Crypto,
+			snapshot.Bullion,
+			snapshot.Total,
+			snapshot.Currency,
+			marker)
+	}
+
+	if incomplete {
+		fmt.Printf("\n* incomplete snapshot, one or more price sources failed\n")
+	}
+	if stale {
+		fmt.Printf("S stale snapshot, one or more price sources used last known prices\n")
+	}
+	if reconstructed {
+		fmt.Printf("R reconstructed from historical prices\n")
+	}
+}
+
+func showTodayData(service *database.PortfolioService) {
+	snapshot, err := service.GetTodaySnapshot()
+	if err != nil {
+		log.Fatal("Failed to get today's data:", err)
+	}
+
+	fmt.Printf("📊 Today's Portfolio (%s)\n", snapshot.Date.Format("2006-01-02"))
+	fmt.Printf("═══════════════════════════════════\n")
+	fmt.Printf("🏦 Trading212: %.2f %s\n", snapshot.Trading212, snapshot.Currency)
+	if len(snapshot.Trading212Accounts) > 1 {
+		for _, account := range snapshot.Trading212Accounts {
+			fmt.Printf("   - %s: %.2f %s\n", account.Symbol, account.Value, snapshot.Currency)
+		}
+	}
+	fmt.Printf("₿ Crypto: %.2f %s\n", snapshot.Crypto, snapshot.Currency)
+	fmt.Printf("🥇 Bullion: %.2f %s\n", snapshot.Bullion, snapshot.Currency)
+	if len(snapshot.EquityAssets) > 0 {
+		fmt.Printf("📈 Stocks: %.2f %s\n", snapshot.Equities, snapshot.Currency)
+	}
+	if len(snapshot.SavingsAccounts) > 0 {
+		fmt.Printf("🏧 Savings: %.2f %s\n", snapshot.Savings, snapshot.Currency)
+		for _, account := range snapshot.SavingsAccounts {
+			fmt.Printf("   - %s: %.2f %s\n", account.Symbol, account.Value, snapshot.Currency)
+		}
+	}
+	fmt.Printf("💎 Total: %.2f %s\n", snapshot.Total, snapshot.Currency)
+	if len(snapshot.Positions) > 0 {
+		fmt.Printf("───────────────────────────────────\n")
+		for _, position := range snapshot.Positions {
+			name := position.Name
+			if name == "" {
+				name = position.Symbol
+			}
+			if position.Account != "" {
+				name = position.Account + "/" + name
+			}
+			fmt.Printf("📄 %s: %.2f %s (P&L %+.2f)\n", name, position.Value, snapshot.Currency, position.PnL)
+		}
+	}
+	for _, cash := range snapshot.Trading212Cash {
+		label := "💵 Cash"
+		if cash.Account != "" {
+			label += " " + cash.Account
+		}
+		fmt.Printf("%s: %.2f free, %.2f invested, %.2f result, %.2f in pies\n",
+			label, cash.Free, cash.Invested, cash.Result, cash.PieCash)
+	}
+	fmt.Printf("───────────────────────────────────\n")
+	if len(snapshot.MissingSources) > 0 {
+		fmt.Printf("⚠️ Incomplete, missing: %s\n", strings.Join(snapshot.MissingSources, ", "))
+	}
+	if len(snapshot.StaleSources) > 0 {
+		fmt.Printf("⚠️ Last known prices from: %s\n", strings.Join(snapshot.StaleSources, ", "))
+	}
+	fmt.Printf("Recorded at: %s\n", snapshot.CreatedAt.Format("2006-01-02 15:04:05"))
+}
+
+func showPositionHistory(service *database.PortfolioService, ticker string, days int) {
+	history, err := service.GetPositionHistory(ticker, days)
+	if err != nil {
+		log.Fatal("Failed to get position history:", err)
+	}
+	if len(history) == 0 {
+		fmt.Printf("No %s position recorded in the last %d days\n", ticker, days)
+		return
+	}
+
+	name := ticker
+	if latest := history[len(history)-1]; latest.Name != "" {
+		name = fmt.Sprintf("%s (%s, %s %s)", latest.Name, ticker, latest.InstrumentType, latest.InstrumentCurrency)
+	}
+
+	fmt.Printf("📄 %s, last %d days\n", name, days)
+	fmt.Printf("═══════════════════════════════════════════════════════════════\n")
+	fmt.Printf("%-12s %-10s %-12s %-12s %-12s %-12s %-8s\n", "Date", "Account", "Quantity", "Price", "Value", "P&L", "Currency")
+	fmt.Printf("───────────────────────────────────────────────────────────────\n")
+	for _, position := range history {
+		fmt.Printf("%-12s %-10s %-12.4f %-12.2f %-12.2f %-12.2f %-8s\n",
+			position.AsOf.Format("2006-01-02"),
+			position.Account,
+			position.Amount,
+			position.Price,
+			position.Value,
+			position.PnL,
+			position.Currency)
+	}
+}
+
+func cleanupOldData(service *database.PortfolioService, daysToKeep int) {
+	fmt.Printf("Cleaning up data older than %d days...\n", daysToKeep)
+	err := service.DeleteOldSnapshots(daysToKeep)
+	if err != nil {
+		log.Fatal("Failed to cleanup old data:", err)
+	}
+	fmt.Println("Cleanup completed!")
+}
+
+func migrateCurrency(service *database.PortfolioService, currency string) {
+	fmt.Printf("Converting portfolio snapshots to %s...\n", currency)
+	migrated, err := service.MigrateCurrency(currency)
+	if err != nil {
+		log.Fatal("Failed to migrate snapshots:", err)
+	}
+	fmt.Printf("Migration completed, %d snapshots converted!\n", migrated)
+}
+
+func backfill(service *database.PortfolioService, holdings *database.HoldingsService, fromDate, toDate string) {
+	from, err := time.Parse("2006-01-02", fromDate)
+	if err != nil {
+		log.Fatal("Invalid --from date:", fromDate)
+	}
+
+	to := time.Now().AddDate(0, 0, -1).Truncate(24 * time.Hour)
+	if toDate != "" {
+		to, err = time.Parse("2006-01-02", toDate)
+		if err != nil {
+			log.Fatal("Invalid --to date:", toDate)
+		}
+	}
+	if to.Before(from) {
+		log.Fatal("--to must not be before --from")
+	}
+
+	missing, err := service.MissingDays(from, to)
+	if err != nil {
+		log.Fatal("Failed to find missed days:", err)
+	}
+	if len(missing) == 0 {
+		fmt.Println("No missed days in that range.")
+		return
+	}
+
+	engine, err := valuation.NewEngine(valuation.DefaultHoldingsPath)
+	if err != nil {
+		log.Fatal("Failed to set up valuation:", err)
+	}
+	engine.SetPriceHistory(service)
+	engine.SetHoldingsStore(holdings)
+
+	fmt.Printf("Rebuilding %d missed days from historical prices...\n", len(missing))
+	rebuilt := 0
+	for _, day := range missing {
+		v, err := engine.ValueAt(context.Background(), day)
+		if err != nil {
+			log.Printf("Skipping %s: %v", day.Format("2006-01-02"), err)
+			continue
+		}
+		if err := service.SaveSnapshot(day, v); err != nil {
+			log.Printf("Skipping %s: %v", day.Format("2006-01-02"), err)
+			continue
+		}
+		rebuilt++
+	}
+
+	fmt.Printf("Backfill completed, %d of %d days rebuilt!\n", rebuilt, len(missing))
+}
+
+func exportTrading212(args []string) {
+	exportFlags := flag.NewFlagSet("export-trading212", flag.ExitOnError)
+	from := exportFlags.String("from", "", "start of the export (YYYY-MM-DD)")
+	to := exportFlags.String("to", "", "end of the export (YYYY-MM-DD), defaults to now")
+	out := exportFlags.String("out", "", "write the CSV to this file instead of the database")
+	accountName := exportFlags.String("account", "", "Trading212 account to export, required when several are configured")
+	exportFlags.Parse(args)
+
+	if *from == "" {
+		fmt.Println("Usage: go run ./cmd/database export-trading212 --from YYYY-MM-DD [--to YYYY-MM-DD] [--account name] [--out file.csv]")
+		os.Exit(1)
+	}
+
+	timeFrom, err := time.Parse("2006-01-02", *from)
+	if err != nil {
+		log.Fatal("Invalid --from date:", *from)
+	}
+	timeTo := time.Now()
+	if *to != "" {
+		timeTo, err = time.Parse("2006-01-02", *to)
+		if err != nil {
+			log.Fatal("Invalid --to date:", *to)
+		}
+		// Include the whole last day
+		timeTo = timeTo.Add(24*time.Hour - time.Second)
+	}
+
+	account, err := stocks.FindAccount(*accountName)
+	if err != nil {
+		log.Fatal("Failed to create Trading212 client:", err)
+	}
+	client := stocks.NewClient(account.APIKey, account.IsLive)
+
+	// Trading212 can take several minutes to prepare a report
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	fmt.Printf("Requesting %s export from %s to %s...\n", account.Name, timeFrom.Format("2006-01-02"), timeTo.Format("2006-01-02"))
+	data, err := client.Export(ctx, stocks.ExportRequest{
+		DataIncluded: stocks.ExportAll,
+		TimeFrom:     timeFrom,
+		TimeTo:       timeTo,
+	})
+	if err != nil {
+		log.Fatal("Failed to export Trading212 history:", err)
+	}
+
+	if *out != "" {
+		if err := os.WriteFile(*out, data, 0o644); err != nil {
+			log.Fatal("Failed to write export:", err)
+		}
+		fmt.Printf("Export saved to %s\n", *out)
+		return
+	}
+
+	rows, err := stocks.ParseExport(bytes.NewReader(data))
+	if err != nil {
+		log.Fatal("Failed to parse export:", err)
+	}
+
+	db, err := database.NewMongoDB()
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	added, err := database.NewActivityService(db).SaveExportRows(account.Name, rows)
+	if err != nil {
+		log.Fatal("Failed to save export:", err)
+	}
+	fmt.Printf("Export completed, %d rows imported (%d new)!\n", len(rows), added)
+}
+
+// metadataClient returns a client for reading instruments and exchanges,
+// which any configured account can do
+func metadataClient() (*stocks.Client, error) {
+	accounts, err := stocks.LoadAccounts()
+	if err != nil {
+		return nil, err
+	}
+	return stocks.NewClient(accounts[0].APIKey, accounts[0].IsLive), nil
+}
+
+// openCatalogue opens the instrument catalogue, refreshing it when stale or
+// when asked; a failed refresh falls back to the list on disk
+func openCatalogue(refresh bool) *instruments.Catalogue {
+	catalogue, err := instruments.OpenFromEnv()
+	if err != nil {
+		log.Fatal("Failed to open instrument catalogue:", err)
+	}
+	if !refresh && !catalogue.Stale() {
+		return catalogue
+	}
+
+	client, err := metadataClient()
+	if err != nil {
+		log.Printf("Warning: Could not refresh instrument catalogue: %v", err)
+		return catalogue
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	fmt.Println("Downloading the Trading212 instrument list...")
+	if err := catalogue.Refresh(ctx, client); err != nil {
+		log.Printf("Warning: Could not refresh instrument catalogue: %v", err)
+	}
+	return catalogue
+}
+
+func lookupInstrument(args []string) {
+	instrumentFlags := flag.NewFlagSet("instrument", flag.ExitOnError)
+	refresh := instrumentFlags.Bool("refresh", false, "download the instrument list even if it is recent")
+	instrumentFlags.Parse(args)
+
+	if instrumentFlags.NArg() < 1 {
+		fmt.Println("Usage: go run ./cmd/database instrument [--refresh] <ticker|isin|name>")
+		fmt.Println("Example: go run ./cmd/database instrument apple")
+		os.Exit(1)
+	}
+	query := strings.Join(instrumentFlags.Args(), " ")
+
+	catalogue := openCatalogue(*refresh)
+
+	// An exact ticker or ISIN match is listed on its own
+	matches := catalogue.ByISIN(query)
+	if instrument, ok := catalogue.Lookup(query); ok {
+		matches = []stocks.Instrument{instrument}
+	}
+	if len(matches) == 0 {
+		matches = catalogue.Search(query)
+	}
+	if len(matches) == 0 {
+		fmt.Printf("No instrument matches %q\n", query)
+		return
+	}
+
+	fmt.Printf("%-18s %-14s %-8s %-5s %s\n", "Ticker", "ISIN", "Type", "Cur", "Name")
+	fmt.Printf("───────────────────────────────────────────────────────────────\n")
+	for _, instrument := range matches {
+		fmt.Printf("%-18s %-14s %-8s %-5s %s\n",
+			instrument.Ticker, instrument.ISIN, instrument.Type, instrument.CurrencyCode, instrument.Name)
+	}
+	fmt.Printf("\nCatalogue from %s\n", catalogue.FetchedAt().Format("2006-01-02 15:04"))
+}
+
+func showMarketHours(query string) {
+	instrument, err := openCatalogue(false).Resolve(query)
+	if err != nil {
+		log.Fatal("Failed to resolve instrument:", err)
+	}
+
+	client, err := metadataClient()
+	if err != nil {
+		log.Fatal("Failed to create Trading212 client:", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	calendar := market.OpenFromEnv()
+	if err := calendar.EnsureFresh(ctx, client); err != nil {
+		log.Fatal("Failed to get exchange schedules:", err)
+	}
+
+	now := time.Now()
+	open, err := calendar.InstrumentOpen(instrument, now)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("🏛️ %s (%s) on %s\n", instrument.Name, instrument.Ticker, calendar.Exchange(instrument.WorkingScheduleID))
+	if open {
+		fmt.Println("Market is open")
+	} else {
+		fmt.Println("Market is closed")
+	}
+	if closeAt, err := calendar.InstrumentNextClose(instrument, now); err == nil {
+		fmt.Printf("Next close: %s\n", closeAt.Local().Format("Mon 2006-01-02 15:04 MST"))
+	}
+	if openAt, err := calendar.NextOpen(instrument.WorkingScheduleID, now); err == nil {
+		fmt.Printf("Next open:  %s\n", openAt.Local().Format("Mon 2006-01-02 15:04 MST"))
+	}
+}
@@ -0,0 +1,470 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"investment-tracker/internal/conversion"
+	"investment-tracker/internal/crypto"
+	"investment-tracker/internal/database"
+	"investment-tracker/internal/fx"
+	"investment-tracker/internal/portfolio"
+	"investment-tracker/internal/valuation"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load environment variables from .env file (optional)
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Info: No .env file found, using environment variables: %v", err)
+	}
+
+	offline := flag.Bool("offline", false, "check the schema only, without asking the price providers")
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) < 1 {
+		fmt.Println("Usage:")
+		fmt.Println("  go run ./cmd/holdings [--offline] validate [path]  - Check a holdings file, config/holdings.json by default")
+		fmt.Println("  go run ./cmd/holdings list [--as-of YYYY-MM-DD]  - Show the stored holdings, now or on a past day")
+		fmt.Println("  go run ./cmd/holdings add <section> <name> <value> --reason text     - Add a holding")
+		fmt.Println("  go run ./cmd/holdings set <section> <name> <value> --reason text     - Add or replace a holding")
+		fmt.Println("  go run ./cmd/holdings remove <section> <name> --reason text          - Remove a holding")
+		fmt.Println("  go run ./cmd/holdings import [path] [--reason text]  - Replace the stored holdings with a holdings file")
+		fmt.Println("  go run ./cmd/holdings export [path] [--as-of YYYY-MM-DD]  - Write the stored holdings as a holdings file")
+		fmt.Println("  go run ./cmd/holdings history [section] [name] [--days n]  - Show the changes to the holdings")
+		fmt.Println("")
+		fmt.Println("Sections are crypto, bullion, stocks and savings; values are written as in the holdings file")
+		os.Exit(1)
+	}
+
+	// Validating a file needs no database connection
+	if args[0] == "validate" {
+		path := valuation.DefaultHoldingsPath
+		if len(args) > 1 {
+			path = args[1]
+		}
+		os.Exit(validate(path, *offline))
+	}
+
+	commandFlags := flag.NewFlagSet(args[0], flag.ExitOnError)
+	reason := commandFlags.String("reason", "", "why the holdings changed, recorded in the history")
+	asOf := commandFlags.String("as-of", "", "show the holdings as they stood at the end of a day (YYYY-MM-DD)")
+	days := commandFlags.Int("days", 0, "only show changes from the last N days")
+	positional := parseCommand(commandFlags, args[1:])
+
+	var day time.Time
+	if *asOf != "" {
+		parsed, err := time.Parse(portfolio.DateLayout, *asOf)
+		if err != nil {
+			log.Fatal("Invalid --as-of date:", *asOf)
+		}
+		day = time.Date(parsed.Year(), parsed.Month(), parsed.Day(), 23, 59, 59, 0, time.UTC)
+	}
+
+	db, err := database.NewMongoDB()
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	holdings := database.NewHoldingsService(db)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "list":
+		listHoldings(ctx, holdings, day)
+
+	case "add", "set":
+		if len(positional) != 3 {
+			fmt.Printf("Usage: go run ./cmd/holdings %s <section> <name> <value> --reason text\n", args[0])
+			fmt.Println("Example: go run ./cmd/holdings set crypto BTC 0.25 --reason \"bought the dip\"")
+			os.Exit(1)
+		}
+		section, name, value := positional[0], positional[1], []byte(positional[2])
+		checkSection(section)
+		if !json.Valid(value) {
+			log.Fatalf("Invalid value %s, expected JSON such as 0.25 or '[{\"quantity\": 0.25, ...}]'", value)
+		}
+
+		change := holdings.Set
+		if args[0] == "add" {
+			change = holdings.Add
+		}
+		if err := change(ctx, section, name, value, *reason); err != nil {
+			log.Fatal("Failed to change holdings: ", err)
+		}
+		fmt.Printf("✅ %s %s %s\n", args[0], section, name)
+
+	case "remove":
+		if len(positional) != 2 {
+			fmt.Println("Usage: go run ./cmd/holdings remove <section> <name> --reason text")
+			os.Exit(1)
+		}
+		checkSection(positional[0])
+		if err := holdings.Remove(ctx, positional[0], positional[1], *reason); err != nil {
+			log.Fatal("Failed to change holdings: ", err)
+		}
+		fmt.Printf("✅ removed %s %s\n", positional[0], positional[1])
+
+	case "import":
+		path := valuation.DefaultHoldingsPath
+		if len(positional) > 0 {
+			path = positional[0]
+		}
+		importHoldings(ctx, holdings, path, *reason)
+
+	case "export":
+		path := ""
+		if len(positional) > 0 {
+			path = positional[0]
+		}
+		exportHoldings(ctx, holdings, path, day)
+
+	case "history":
+		var section, name string
+		if len(positional) > 0 {
+			section = positional[0]
+			checkSection(section)
+		}
+		if len(positional) > 1 {
+			name = positional[1]
+		}
+		var since time.Time
+		if *days > 0 {
+			since = time.Now().AddDate(0, 0, -*days)
+		}
+		showHistory(ctx, holdings, section, name, since)
+
+	default:
+		fmt.Printf("Unknown command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// parseCommand parses a command's flags, which may follow its arguments
+func parseCommand(flags *flag.FlagSet, args []string) []string {
+	var positional []string
+	for len(args) > 0 {
+		flags.Parse(args)
+		args = flags.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+	return positional
+}
+
+func checkSection(section string) {
+	for _, known := range portfolio.Sections {
+		if section == known {
+			return
+		}
+	}
+	log.Fatalf("Unknown section %q, expected one of %s", section, strings.Join(portfolio.Sections, ", "))
+}
+
+// loadStored returns the stored holdings now, or at a past time when set
+func loadStored(ctx context.Context, holdings *database.HoldingsService, asOf time.Time) *portfolio.Holdings {
+	var h *portfolio.Holdings
+	var err error
+	if asOf.IsZero() {
+		h, err = holdings.CurrentHoldings(ctx)
+	} else {
+		h, err = holdings.HoldingsAt(ctx, asOf)
+	}
+	if err != nil {
+		log.Fatal("Failed to load holdings: ", err)
+	}
+	return h
+}
+
+func listHoldings(ctx context.Context, holdings *database.HoldingsService, asOf time.Time) {
+	h := loadStored(ctx, holdings, asOf)
+
+	if asOf.IsZero() {
+		fmt.Println("📒 Holdings")
+	} else {
+		fmt.Printf("📒 Holdings on %s\n", asOf.Format(portfolio.DateLayout))
+	}
+	fmt.Printf("═══════════════════════════════════════\n")
+
+	listed := 0
+	for _, name := range sortedKeys(h.Crypto) {
+		fmt.Printf("%-8s %-20s %s\n", portfolio.SectionCrypto, name, describeAsset(h.Crypto[name]))
+		listed++
+	}
+	for _, name := range sortedKeys(h.Bullion) {
+		fmt.Printf("%-8s %-20s %s\n", portfolio.SectionBullion, name, describeAsset(h.Bullion[name]))
+		listed++
+	}
+	for _, name := range sortedKeys(h.Stocks) {
+		equity := h.Stocks[name]
+		fmt.Printf("%-8s %-20s %s @ %.2f %s\n", portfolio.SectionStocks, name, describeAsset(equity.Asset()), equity.Price, equity.Currency)
+		listed++
+	}
+	for _, name := range sortedKeys(h.Savings) {
+		savings := h.Savings[name]
+		line := fmt.Sprintf("%.2f %s", savings.Balance, savings.Currency)
+		if savings.InterestRate > 0 {
+			line += fmt.Sprintf(" at %g%%", savings.InterestRate)
+		}
+		if !savings.MaturityDate.IsZero() {
+			line += " until " + savings.MaturityDate.Format(portfolio.DateLayout)
+		}
+		fmt.Printf("%-8s %-20s %s\n", portfolio.SectionSavings, name, line)
+		listed++
+	}
+
+	if listed == 0 {
+		fmt.Println("No holdings")
+	}
+}
+
+// describeAsset renders a quantity and, for lots, how many there are
+func describeAsset(asset portfolio.Asset) string {
+	if !asset.HasLots() {
+		return fmt.Sprintf("%g", asset.Quantity())
+	}
+	return fmt.Sprintf("%g in %d lot(s)", asset.Quantity(), len(asset.Lots))
+}
+
+func importHoldings(ctx context.Context, holdings *database.HoldingsService, path, reason string) {
+	h, err := portfolio.LoadHoldings(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if reason == "" {
+		reason = "import from " + path
+	}
+
+	changed, err := holdings.Import(ctx, h, reason)
+	if err != nil {
+		log.Fatal("Failed to import holdings: ", err)
+	}
+	fmt.Printf("✅ Imported %s: %d holding(s) changed\n", path, changed)
+}
+
+// exportHoldings writes the stored holdings as a holdings file, or to stdout
+// without a path
+func exportHoldings(ctx context.Context, holdings *database.HoldingsService, path string, asOf time.Time) {
+	h := loadStored(ctx, holdings, asOf)
+
+	data, err := portfolio.EncodeHoldings(h)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if path == "" || path == "-" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		log.Fatal("Failed to write holdings: ", err)
+	}
+	fmt.Printf("✅ Exported holdings to %s\n", path)
+}
+
+func showHistory(ctx context.Context, holdings *database.HoldingsService, section, name string, since time.Time) {
+	changes, err := holdings.History(ctx, section, name, since)
+	if err != nil {
+		log.Fatal("Failed to get holdings history: ", err)
+	}
+
+	fmt.Println("🕓 Holdings history")
+	fmt.Printf("═══════════════════════════════════════\n")
+	if len(changes) == 0 {
+		fmt.Println("No changes")
+		return
+	}
+
+	for _, change := range changes {
+		before, err := database.ValueJSON(change.Before)
+		if err != nil {
+			log.Fatal(err)
+		}
+		after, err := database.ValueJSON(change.After)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("%s %-6s %-8s %-20s", change.Time.Local().Format("2006-01-02 15:04:05"), change.Action, change.Section, change.Name)
+		switch {
+		case before == nil:
+			fmt.Printf(" %s", after)
+		case after == nil:
+			fmt.Printf(" was %s", before)
+		default:
+			fmt.Printf(" %s → %s", before, after)
+		}
+		fmt.Printf(" (%s)\n", change.Reason)
+	}
+}
+
+// validate checks a holdings file against the schema and, unless offline,
+// its symbols and currencies against the configured providers. It prints
+// each issue as file:line:column and returns the exit status: 1 when the file
+// has issues, 2 when it has none but a provider could not be asked
+func validate(path string, offline bool) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("❌ Failed to read holdings file: %v\n", err)
+		return 1
+	}
+
+	// A provider that fails says nothing about the file, so what it could
+	// not check is listed apart from the issues
+	var unchecked []string
+	doc := portfolio.ParseHoldings(path, data)
+	if doc.Holdings != nil && !offline {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		unchecked = append(unchecked, checkSymbols(ctx, doc)...)
+		unchecked = append(unchecked, checkCurrencies(ctx, doc)...)
+		checkBullion(doc)
+	}
+
+	for _, issue := range doc.Issues {
+		fmt.Printf("%s:%s\n", path, issue)
+	}
+	for _, note := range unchecked {
+		fmt.Printf("⚠️  %s: not checked: %s\n", path, note)
+	}
+
+	if len(doc.Issues) > 0 {
+		fmt.Printf("❌ %s: %d issue(s)\n", path, len(doc.Issues))
+		return 1
+	}
+	if len(unchecked) > 0 {
+		fmt.Printf("⚠️  %s: no issues found, but %d check(s) could not be made\n", path, len(unchecked))
+		return 2
+	}
+
+	h := doc.Holdings
+	fmt.Printf("✅ %s is valid: %d crypto, %d bullion, %d stocks, %d savings\n",
+		path, len(h.Crypto), len(h.Bullion), len(h.Stocks), len(h.Savings))
+	return 0
+}
+
+// checkSymbols asks the crypto price providers for every held symbol, so a
+// typo is caught before the valuation reports it as a failed source. Only a
+// symbol every provider answered it does not know is an issue; it returns
+// the symbols that could not be checked
+func checkSymbols(ctx context.Context, doc *portfolio.Document) []string {
+	symbols := sortedKeys(doc.Holdings.Crypto)
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	chain, err := crypto.NewChainFromEnv()
+	if err != nil {
+		return []string{fmt.Sprintf("crypto: %v", err)}
+	}
+
+	quotes, err := chain.GetPrices(ctx, symbols, "USD")
+	if err != nil {
+		return []string{fmt.Sprintf("crypto: %s failed: %v", chain.Name(), err)}
+	}
+
+	var unchecked []string
+	for _, symbol := range symbols {
+		err, ok := quotes.Errors[symbol]
+		if !ok {
+			continue
+		}
+		if allNotFound(err) {
+			doc.Report([]string{"crypto", symbol}, "no price from %s: %v", chain.Name(), err)
+		} else {
+			unchecked = append(unchecked, fmt.Sprintf("crypto.%s: %v", symbol, err))
+		}
+	}
+	return unchecked
+}
+
+// allNotFound reports whether every provider in a chain error answered that
+// it has no price, rather than failing to answer
+func allNotFound(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := joined.Unwrap()
+		for _, err := range errs {
+			if !allNotFound(err) {
+				return false
+			}
+		}
+		return len(errs) > 0
+	}
+	return errors.Is(err, crypto.ErrNotFound)
+}
+
+// checkCurrencies makes sure every currency the file uses converts to the
+// base currency. It returns the currencies that could not be checked because
+// the ECB rates could not be fetched
+func checkCurrencies(ctx context.Context, doc *portfolio.Document) []string {
+	base := valuation.BaseCurrency()
+	checked := make(map[string]error)
+	var unchecked []string
+
+	check := func(path []string, currency string) {
+		if currency == "" || currency == base {
+			return
+		}
+		err, seen := checked[currency]
+		if !seen {
+			_, err = conversion.Default().Rate(ctx, currency, base, time.Now())
+			checked[currency] = err
+		}
+		if errors.Is(err, fx.ErrUnknownCurrency) {
+			doc.Report(path, "cannot convert %s to %s: %v", currency, base, err)
+		} else if err != nil && !seen {
+			unchecked = append(unchecked, fmt.Sprintf("%s: %v", currency, err))
+		}
+	}
+
+	checkLots := func(path []string, lots []portfolio.Lot) {
+		for i, lot := range lots {
+			check(append(path, fmt.Sprint(i), "currency"), lot.Currency)
+		}
+	}
+
+	h := doc.Holdings
+	for _, symbol := range sortedKeys(h.Crypto) {
+		checkLots([]string{"crypto", symbol}, h.Crypto[symbol].Lots)
+	}
+	for _, metal := range sortedKeys(h.Bullion) {
+		checkLots([]string{"bullion", metal}, h.Bullion[metal].Lots)
+	}
+	for _, name := range sortedKeys(h.Stocks) {
+		check([]string{"stocks", name, "currency"}, h.Stocks[name].Currency)
+		checkLots([]string{"stocks", name, "lots"}, h.Stocks[name].Lots)
+	}
+	for _, name := range sortedKeys(h.Savings) {
+		check([]string{"savings", name, "currency"}, h.Savings[name].Currency)
+	}
+	return unchecked
+}
+
+// checkBullion makes sure held metals can be priced at all
+func checkBullion(doc *portfolio.Document) {
+	if len(doc.Holdings.Bullion) > 0 && os.Getenv("BULLION_API_KEY") == "" {
+		doc.Report([]string{"bullion"}, "BULLION_API_KEY is not set, so metals cannot be priced")
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
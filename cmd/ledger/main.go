@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"investment-tracker/internal/conversion"
+	"investment-tracker/internal/database"
+	"investment-tracker/internal/portfolio"
+	"investment-tracker/internal/valuation"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load environment variables from .env file (optional)
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Info: No .env file found, using environment variables: %v", err)
+	}
+
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) < 1 {
+		fmt.Println("Usage:")
+		fmt.Println("  go run ./cmd/ledger add <type> <crypto|bullion> <asset> <quantity> [--price p] [--currency C] [--fee f] [--date YYYY-MM-DD] [--from wallet] [--to wallet] [--note text]")
+		fmt.Println("  go run ./cmd/ledger list [section] [asset]          - Show the transactions")
+		fmt.Println("  go run ./cmd/ledger remove <id>                     - Remove a transaction recorded by mistake")
+		fmt.Println("  go run ./cmd/ledger holdings [--as-of YYYY-MM-DD]   - Show the holdings and cost basis the ledger gives")
+		fmt.Println("  go run ./cmd/ledger pnl [--year YYYY]               - Show the realised P&L")
+		fmt.Println("  go run ./cmd/ledger sync [--reason text]            - Replace the holdings of the assets in the ledger with its lots")
+		fmt.Println("")
+		fmt.Printf("Transaction types: %s\n", strings.Join(portfolio.TransactionTypes, ", "))
+		os.Exit(1)
+	}
+
+	commandFlags := flag.NewFlagSet(args[0], flag.ExitOnError)
+	price := commandFlags.Float64("price", 0, "unit price paid or received, or the market value of a reward or airdrop")
+	currency := commandFlags.String("currency", "", "currency of the price and fee")
+	fee := commandFlags.Float64("fee", 0, "fee in the currency; for a transfer, the network fee in units of the asset")
+	date := commandFlags.String("date", "", "day of the transaction (YYYY-MM-DD), defaults to now")
+	from := commandFlags.String("from", "", "wallet or exchange the units left")
+	to := commandFlags.String("to", "", "wallet or exchange the units arrived at")
+	note := commandFlags.String("note", "", "free-form note")
+	asOf := commandFlags.String("as-of", "", "derive the holdings as they stood at the end of a day (YYYY-MM-DD)")
+	year := commandFlags.Int("year", 0, "only show disposals of a year")
+	reason := commandFlags.String("reason", "ledger sync", "why the holdings changed, recorded in the holdings history")
+	positional := parseCommand(commandFlags, args[1:])
+
+	db, err := database.NewMongoDB()
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	ledger := database.NewLedgerService(db)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "add":
+		if len(positional) != 4 {
+			fmt.Println("Usage: go run ./cmd/ledger add <type> <crypto|bullion> <asset> <quantity> [--price p] [--currency C] [--fee f] [--date YYYY-MM-DD]")
+			fmt.Println("Example: go run ./cmd/ledger add buy crypto BTC 0.05 --price 42000 --currency USD --fee 12.5 --date 2024-01-10")
+			os.Exit(1)
+		}
+		quantity, err := strconv.ParseFloat(positional[3], 64)
+		if err != nil {
+			log.Fatalf("Invalid quantity %q", positional[3])
+		}
+
+		t := portfolio.Transaction{
+			Time:     time.Now(),
+			Type:     positional[0],
+			Section:  positional[1],
+			Asset:    positional[2],
+			Quantity: quantity,
+			Price:    *price,
+			Currency: strings.ToUpper(*currency),
+			Fee:      *fee,
+			From:     *from,
+			To:       *to,
+			Note:     *note,
+		}
+		if *date != "" {
+			t.Time = parseDay(*date)
+		}
+
+		id, err := ledger.Record(ctx, t)
+		if err != nil {
+			log.Fatal("Failed to record transaction: ", err)
+		}
+		fmt.Printf("✅ Recorded %s as %s\n", t, id)
+
+	case "list":
+		var section, asset string
+		if len(positional) > 0 {
+			section = positional[0]
+		}
+		if len(positional) > 1 {
+			asset = positional[1]
+		}
+		listTransactions(ctx, ledger, section, asset)
+
+	case "remove":
+		if len(positional) != 1 {
+			fmt.Println("Usage: go run ./cmd/ledger remove <id>")
+			os.Exit(1)
+		}
+		if err := ledger.Remove(ctx, positional[0]); err != nil {
+			log.Fatal("Failed to remove transaction: ", err)
+		}
+		fmt.Printf("✅ Removed %s\n", positional[0])
+
+	case "holdings":
+		until := time.Now()
+		if *asOf != "" {
+			until = parseDay(*asOf).Add(24*time.Hour - time.Second)
+		}
+		showHoldings(ctx, ledger, until)
+
+	case "pnl":
+		showRealized(ctx, ledger, *year)
+
+	case "sync":
+		syncHoldings(ctx, db, ledger, *reason)
+
+	default:
+		fmt.Printf("Unknown command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// parseCommand parses a command's flags, which may follow its arguments
+func parseCommand(flags *flag.FlagSet, args []string) []string {
+	var positional []string
+	for len(args) > 0 {
+		flags.Parse(args)
+		args = flags.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+	return positional
+}
+
+func parseDay(value string) time.Time {
+	day, err := time.Parse(portfolio.DateLayout, value)
+	if err != nil {
+		log.Fatalf("Invalid date %q, expected YYYY-MM-DD", value)
+	}
+	return day
+}
+
+// replay derives the books from every transaction up to a time
+func replay(ctx context.Context, ledger *database.LedgerService, until time.Time) ([]portfolio.Transaction, *portfolio.Books) {
+	transactions, err := ledger.Transactions(ctx, "", "", until)
+	if err != nil {
+		log.Fatal(err)
+	}
+	books, err := portfolio.Replay(transactions)
+	if err != nil {
+		log.Fatal("The ledger does not replay: ", err)
+	}
+	return transactions, books
+}
+
+func listTransactions(ctx context.Context, ledger *database.LedgerService, section, asset string) {
+	transactions, err := ledger.Transactions(ctx, section, asset, time.Now())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("📒 Ledger")
+	fmt.Printf("═══════════════════════════════════════\n")
+	if len(transactions) == 0 {
+		fmt.Println("No transactions")
+		return
+	}
+
+	for _, t := range transactions {
+		fmt.Printf("%s %s %-14s %-8s %-6s %14g", t.ID, t.Time.Format(portfolio.DateLayout), t.Type, t.Section, t.Asset, t.Quantity)
+		if t.Price > 0 {
+			fmt.Printf(" @ %g %s", t.Price, t.Currency)
+		}
+		if t.Fee > 0 {
+			if t.Type == portfolio.TxTransfer {
+				fmt.Printf(" fee %g %s", t.Fee, t.Asset)
+			} else {
+				fmt.Printf(" fee %g %s", t.Fee, t.Currency)
+			}
+		}
+		if t.From != "" || t.To != "" {
+			fmt.Printf(" %s → %s", t.From, t.To)
+		}
+		if t.Note != "" {
+			fmt.Printf(" (%s)", t.Note)
+		}
+		fmt.Println()
+	}
+}
+
+func showHoldings(ctx context.Context, ledger *database.LedgerService, until time.Time) {
+	_, books := replay(ctx, ledger, until)
+	currency := valuation.BaseCurrency()
+
+	fmt.Printf("📦 Holdings from the ledger on %s\n", until.Format(portfolio.DateLayout))
+	fmt.Printf("═══════════════════════════════════════\n")
+
+	listed := 0
+	for _, section := range portfolio.LedgerSections {
+		assets := books.Section(section)
+		for _, name := range sortedKeys(assets) {
+			asset := assets[name]
+			fmt.Printf("%-8s %-6s %14g in %d lot(s)", section, name, asset.Quantity(), len(asset.Lots))
+
+			cost, err := valuation.CostBasis(ctx, conversion.Default(), currency, asset.Lots, until)
+			if err != nil {
+				fmt.Printf(", cost unavailable: %v\n", err)
+			} else {
+				fmt.Printf(", cost %.2f %s (%.2f per unit)\n", cost, currency, cost/asset.Quantity())
+			}
+			listed++
+		}
+	}
+
+	if listed == 0 {
+		fmt.Println("Nothing held")
+	}
+}
+
+func showRealized(ctx context.Context, ledger *database.LedgerService, year int) {
+	_, books := replay(ctx, ledger, time.Now())
+	currency := valuation.BaseCurrency()
+
+	title := "💰 Realised P&L"
+	if year != 0 {
+		title += fmt.Sprintf(" in %d", year)
+	}
+	fmt.Println(title)
+	fmt.Printf("═══════════════════════════════════════\n")
+
+	var total valuation.Performance
+	shown := 0
+	for _, r := range books.Realized {
+		if year != 0 && r.Time.Year() != year {
+			continue
+		}
+
+		realized, err := valuation.Realized(ctx, conversion.Default(), currency, r)
+		if err != nil {
+			log.Printf("Warning: Skipping %s %s on %s: %v", r.Type, r.Asset, r.Time.Format(portfolio.DateLayout), err)
+			continue
+		}
+		total.Cost += realized.Cost
+		total.Value += realized.Value
+		shown++
+
+		fmt.Printf("%s %-14s %-6s %14g proceeds %12.2f cost %12.2f P&L %+12.2f %s\n",
+			r.Time.Format(portfolio.DateLayout), r.Type, r.Asset, r.Quantity, realized.Value, realized.Cost, realized.PnL(), currency)
+	}
+
+	if shown == 0 {
+		fmt.Println("No disposals")
+		return
+	}
+	fmt.Printf("\nTotal: proceeds %.2f, cost %.2f, P&L %+.2f %s\n", total.Value, total.Cost, total.PnL(), currency)
+}
+
+// syncHoldings replaces the holdings of the assets the ledger has
+// transactions for with the lots it derives, in the holdings file or the
+// database, whichever HOLDINGS_SOURCE names. Assets the ledger does not know
+// are kept as they are
+func syncHoldings(ctx context.Context, db *database.MongoDB, ledger *database.LedgerService, reason string) {
+	transactions, books := replay(ctx, ledger, time.Now())
+
+	tracked := make(map[string]map[string]bool)
+	for _, t := range transactions {
+		if tracked[t.Section] == nil {
+			tracked[t.Section] = make(map[string]bool)
+		}
+		tracked[t.Section][t.Asset] = true
+	}
+	if len(tracked) == 0 {
+		fmt.Println("The ledger is empty, nothing to sync")
+		return
+	}
+
+	source, err := valuation.HoldingsSource()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var h *portfolio.Holdings
+	var store *database.HoldingsService
+	if source == valuation.HoldingsSourceMongoDB {
+		store = database.NewHoldingsService(db)
+		h, err = store.CurrentHoldings(ctx)
+	} else {
+		h, err = portfolio.LoadHoldings(valuation.DefaultHoldingsPath)
+	}
+	if err != nil {
+		log.Fatal("Failed to load holdings: ", err)
+	}
+
+	h.Crypto = mergeAssets(portfolio.SectionCrypto, h.Crypto, books.Crypto, tracked[portfolio.SectionCrypto])
+	h.Bullion = mergeAssets(portfolio.SectionBullion, h.Bullion, books.Bullion, tracked[portfolio.SectionBullion])
+
+	if store != nil {
+		changed, err := store.Import(ctx, h, reason)
+		if err != nil {
+			log.Fatal("Failed to update holdings: ", err)
+		}
+		fmt.Printf("✅ Synced the ledger into the database holdings: %d holding(s) changed\n", changed)
+		return
+	}
+
+	if err := portfolio.SaveHoldings(valuation.DefaultHoldingsPath, h); err != nil {
+		log.Fatal("Failed to update holdings: ", err)
+	}
+	fmt.Printf("✅ Synced the ledger into %s\n", valuation.DefaultHoldingsPath)
+}
+
+// mergeAssets replaces the tracked assets of a section with the ledger's,
+// dropping those it no longer holds, and keeps the rest
+func mergeAssets(section string, current, derived map[string]portfolio.Asset, tracked map[string]bool) map[string]portfolio.Asset {
+	if len(tracked) == 0 {
+		return current
+	}
+
+	merged := make(map[string]portfolio.Asset, len(current))
+	var kept []string
+	for name, asset := range current {
+		if !tracked[name] {
+			merged[name] = asset
+			kept = append(kept, name)
+		}
+	}
+	for name := range tracked {
+		if asset, ok := derived[name]; ok {
+			merged[name] = asset
+		}
+	}
+
+	if len(kept) > 0 {
+		sort.Strings(kept)
+		fmt.Printf("Keeping %s %s, which the ledger has no transactions for\n", section, strings.Join(kept, ", "))
+	}
+	return merged
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"investment-tracker/internal/database"
+	"investment-tracker/internal/instruments"
+	"investment-tracker/internal/market"
+	"investment-tracker/internal/notifications"
+	"investment-tracker/internal/stocks"
+	"investment-tracker/internal/valuation"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load environment variables from .env file (optional)
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Info: No .env file found, using environment variables: %v", err)
+	}
+
+	noCache := flag.Bool("no-cache", false, "ignore cached prices and fetch everything live")
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) < 1 {
+		fmt.Println("Usage:")
+		fmt.Println("  go run ./cmd/notify [--no-cache] test           - Send test notification")
+		fmt.Println("  go run ./cmd/notify [--no-cache] now            - Send notification now")
+		fmt.Println("  go run ./cmd/notify [--no-cache] schedule 8 30  - Schedule daily at 8:30 AM")
+		fmt.Println("  go run ./cmd/notify [--no-cache] schedule-close AAPL_US_EQ 15 - Schedule 15 minutes after each close of a market")
+		os.Exit(1)
+	}
+
+	command := args[0]
+
+	notificationService := notifications.NewNotificationService()
+	scheduler := notifications.NewScheduler(notificationService)
+	engine, err := valuation.NewEngine(valuation.DefaultHoldingsPath)
+	if err != nil {
+		log.Fatal("Failed to set up valuation:", err)
+	}
+	if *noCache {
+		engine.ForceRefresh()
+	}
+
+	// Save every sent notification to the database
+	scheduler.SetOnNotificationSent(func(v *valuation.Valuation) {
+		if err := savePortfolioToDatabase(v); err != nil {
+			log.Printf("Warning: Failed to save to database: %v", err)
+		}
+	})
+
+	switch command {
+	case "test":
+		fmt.Println("Sending test notification...")
+		err := notificationService.TestNotifications()
+		if err != nil {
+			log.Fatal("Test notification failed:", err)
+		}
+		fmt.Println("Test notification sent successfully!")
+
+	case "now":
+		useDatabaseHistory(engine)
+
+		fmt.Println("Sending current portfolio notification...")
+		err := scheduler.SendNow(engine.Value)
+		if err != nil {
+			log.Fatal("Failed to send notification:", err)
+		}
+
+		fmt.Println("Notification sent successfully!")
+
+	case "schedule":
+		if len(args) < 3 {
+			fmt.Println("Usage: go run ./cmd/notify schedule <hour> <minute>")
+			fmt.Println("Example: go run ./cmd/notify schedule 8 30")
+			os.Exit(1)
+		}
+
+		hour, err := strconv.Atoi(args[1])
+		if err != nil || hour < 0 || hour > 23 {
+			log.Fatal("Invalid hour (must be 0-23):", args[1])
+		}
+
+		minute, err := strconv.Atoi(args[2])
+		if err != nil || minute < 0 || minute > 59 {
+			log.Fatal("Invalid minute (must be 0-59):", args[2])
+		}
+
+		useDatabaseHistory(engine)
+
+		fmt.Printf("Starting daily notification scheduler for %02d:%02d...\n", hour, minute)
+
+		scheduler.Start(hour, minute, engine.Value)
+
+		// Keep the program running
+		select {}
+
+	case "schedule-close":
+		if len(args) < 2 {
+			fmt.Println("Usage: go run ./cmd/notify schedule-close <ticker|isin|name> [minutes after close]")
+			fmt.Println("Example: go run ./cmd/notify schedule-close AAPL_US_EQ 15")
+			os.Exit(1)
+		}
+
+		delay := 15 * time.Minute
+		if len(args) > 2 {
+			minutes, err := strconv.Atoi(args[2])
+			if err != nil || minutes < 0 {
+				log.Fatal("Invalid minutes after close:", args[2])
+			}
+			delay = time.Duration(minutes) * time.Minute
+		}
+
+		next, name, err := afterClose(args[1], delay)
+		if err != nil {
+			log.Fatal("Failed to schedule after the close:", err)
+		}
+
+		useDatabaseHistory(engine)
+
+		fmt.Printf("Starting notification scheduler for %s after each %s close...\n", delay, name)
+
+		scheduler.StartAt(next, engine.Value)
+
+		// Keep the program running
+		select {}
+
+	default:
+		fmt.Printf("Unknown command: %s\n", command)
+		os.Exit(1)
+	}
+}
+
+// afterClose resolves an instrument and returns the times just after each close
+// of its market, refreshing the exchange schedules as they run out
+func afterClose(query string, delay time.Duration) (notifications.NextRunFunc, string, error) {
+	// Any account can read the instrument list and the exchange schedules
+	accounts, err := stocks.LoadAccounts()
+	if err != nil {
+		return nil, "", err
+	}
+	client := stocks.NewClient(accounts[0].APIKey, accounts[0].IsLive)
+
+	catalogue, err := instruments.OpenFromEnv()
+	if err != nil {
+		return nil, "", err
+	}
+	calendar := market.OpenFromEnv()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	if err := catalogue.EnsureFresh(ctx, client); err != nil {
+		return nil, "", err
+	}
+	instrument, err := catalogue.Resolve(query)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := calendar.EnsureFresh(ctx, client); err != nil {
+		return nil, "", err
+	}
+
+	name := strings.TrimSpace(calendar.Exchange(instrument.WorkingScheduleID) + " (" + instrument.Name + ")")
+
+	next := func(after time.Time) (time.Time, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := calendar.EnsureFresh(ctx, client); err != nil {
+			return time.Time{}, err
+		}
+
+		closeAt, err := calendar.InstrumentNextClose(instrument, after.Add(-delay))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return closeAt.Add(delay), nil
+	}
+
+	return next, name, nil
+}
+
+// useDatabaseHistory lets the engine fall back to the last prices stored in
+// snapshots and read the holdings from the database when HOLDINGS_SOURCE is
+// mongodb; without a database, failed sources simply stay missing. The
+// connection stays open for the life of the process
+func useDatabaseHistory(engine *valuation.Engine) {
+	db, err := database.NewMongoDB()
+	if err != nil {
+		log.Printf("Warning: No database for last known prices: %v", err)
+		return
+	}
+
+	engine.SetPriceHistory(database.NewPortfolioService(db))
+	engine.SetHoldingsStore(database.NewHoldingsService(db))
+}
+
+func savePortfolioToDatabase(v *valuation.Valuation) error {
+	// Connect to database
+	db, err := database.NewMongoDB()
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	// Save snapshot
+	portfolioService := database.NewPortfolioService(db)
+	err = portfolioService.SaveDailySnapshot(v)
+	if err != nil {
+		return fmt.Errorf("failed to save portfolio snapshot: %w", err)
+	}
+
+	log.Printf("Successfully saved portfolio snapshot to database")
+	return nil
+}
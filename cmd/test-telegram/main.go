@@ -52,7 +52,7 @@ func main() {
 
 	// Send test investment update
 	fmt.Println("\n3. Sending test investment update...")
-	err = notifier.SendInvestmentUpdate(5000.00, 2000.00, 1500.00, 1500.00)
+	err = notifier.SendInvestmentUpdate(notifications.SampleValuation())
 	if err != nil {
 		log.Fatalf("Failed to send investment update: %v", err)
 	}
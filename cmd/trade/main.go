@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"investment-tracker/internal/conversion"
+	"investment-tracker/internal/database"
+	"investment-tracker/internal/instruments"
+	"investment-tracker/internal/stocks"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load environment variables from .env file (optional)
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Info: No .env file found, using environment variables: %v", err)
+	}
+
+	accountName := flag.String("account", "", "Trading212 account to trade on (required with several accounts)")
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) < 1 {
+		fmt.Println("Usage:")
+		fmt.Println("  go run ./cmd/trade [--account name] buy <ticker|isin|name> <qty> [--limit p] [--stop p] [--price p] [--tif DAY|GOOD_TILL_CANCEL] [--dry-run]")
+		fmt.Println("  go run ./cmd/trade [--account name] sell <ticker|isin|name> <qty> [--limit p] [--stop p] [--price p] [--tif DAY|GOOD_TILL_CANCEL] [--dry-run]")
+		fmt.Println("  go run ./cmd/trade [--account name] buy <ticker|isin|name> --value amount [--price p] [--dry-run]")
+		fmt.Println("  go run ./cmd/trade [--account name] cancel <order id>")
+		fmt.Println("  go run ./cmd/trade audit [days]")
+		fmt.Println("")
+		fmt.Println("Orders are disabled unless TRADING212_TRADING_ENABLED=true; see the README for the guardrails")
+		os.Exit(1)
+	}
+
+	// Every order attempt is audited, so there is no trading without the database
+	db, err := database.NewMongoDB()
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	audit := database.NewOrderAuditService(db)
+
+	switch args[0] {
+	case "buy", "sell":
+		placeOrder(audit, *accountName, args[0], args[1:])
+
+	case "cancel":
+		if len(args) < 2 {
+			fmt.Println("Usage: go run ./cmd/trade cancel <order id>")
+			os.Exit(1)
+		}
+		orderID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid order id %q", args[1])
+		}
+		cancelOrder(audit, *accountName, orderID)
+
+	case "audit":
+		days := 7
+		if len(args) > 1 {
+			if d, err := strconv.Atoi(args[1]); err == nil {
+				days = d
+			}
+		}
+		showAudit(audit, days)
+
+	default:
+		fmt.Printf("Unknown command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// newTrader sets up order placement on an account, failing unless trading is enabled for it
+func newTrader(ctx context.Context, audit stocks.OrderAuditLog, accountName string, catalogue *instruments.Catalogue) (*stocks.Trader, *stocks.Client) {
+	config, err := stocks.LoadTradingConfig()
+	if err != nil {
+		log.Fatal("Invalid trading configuration:", err)
+	}
+	if !config.Enabled {
+		log.Fatal(stocks.ErrTradingDisabled, ": set TRADING212_TRADING_ENABLED=true to allow orders")
+	}
+
+	if accountName == "" {
+		accountName = config.Account
+	}
+	account, err := stocks.FindAccount(accountName)
+	if err != nil {
+		log.Fatal("Failed to find account:", err)
+	}
+
+	client := stocks.NewClient(account.APIKey, account.IsLive)
+	info, err := client.GetAccountInfo(ctx)
+	if err != nil {
+		log.Fatal("Failed to get account currency:", err)
+	}
+
+	// The caps are in the account currency, instrument prices are converted into it
+	value := func(ctx context.Context, ticker string, amount float64) (float64, error) {
+		instrument, ok := catalogue.Lookup(ticker)
+		if !ok || instrument.CurrencyCode == "" {
+			return 0, fmt.Errorf("unknown currency of %s", ticker)
+		}
+		rate, err := conversion.Default().Rate(ctx, instrument.CurrencyCode, info.CurrencyCode, time.Now())
+		if err != nil {
+			return 0, err
+		}
+		return amount * rate, nil
+	}
+
+	// Trading212 only quotes instruments in a position, so only those can be
+	// bought or sold at market
+	price := func(ctx context.Context, ticker string) (float64, error) {
+		position, err := client.GetPosition(ctx, ticker)
+		if err != nil {
+			return 0, err
+		}
+		return position.CurrentPrice, nil
+	}
+
+	trader, err := stocks.NewTrader(*account, info.CurrencyCode, config, audit, catalogue.Lookup, price, value)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return trader, client
+}
+
+func placeOrder(audit *database.OrderAuditService, accountName, side string, args []string) {
+	orderFlags := flag.NewFlagSet(side, flag.ExitOnError)
+	limit := orderFlags.Float64("limit", 0, "limit price; with --stop a stop-limit order is placed")
+	stop := orderFlags.Float64("stop", 0, "stop price; a market order is placed without --limit or --stop")
+	amount := orderFlags.Float64("value", 0, "size a market order by cash amount in the instrument currency instead of by quantity")
+	price := orderFlags.Float64("price", 0, "expected price of a market order, refused if more than 5% off the live price")
+	tif := orderFlags.String("tif", string(stocks.TimeInForceDay), "time in force of a limit or stop order: DAY or GOOD_TILL_CANCEL")
+	dryRun := orderFlags.Bool("dry-run", false, "check the order against the guardrails without placing it")
+
+	// Flags may follow the instrument and quantity
+	var positional []string
+	for len(args) > 0 {
+		orderFlags.Parse(args)
+		args = orderFlags.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+	if len(positional) != 2 && !(len(positional) == 1 && *amount > 0) {
+		fmt.Printf("Usage: go run ./cmd/trade %s <ticker|isin|name> <qty> [--limit p] [--stop p] [--price p] [--tif DAY|GOOD_TILL_CANCEL] [--dry-run]\n", side)
+		os.Exit(1)
+	}
+
+	var quantity float64
+	if len(positional) == 2 {
+		q, err := strconv.ParseFloat(positional[1], 64)
+		if err != nil || q <= 0 {
+			log.Fatalf("Invalid quantity %q, expected a positive number", positional[1])
+		}
+		quantity = q
+	}
+	if side == "sell" {
+		quantity, *amount = -quantity, -*amount
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	catalogue, err := instruments.OpenFromEnv()
+	if err != nil {
+		log.Fatal("Failed to open instrument catalogue:", err)
+	}
+	trader, client := newTrader(ctx, audit, accountName, catalogue)
+	if err := catalogue.EnsureFresh(ctx, client); err != nil {
+		log.Fatal("Failed to load instrument catalogue:", err)
+	}
+
+	instrument, err := catalogue.Resolve(positional[0])
+	if err != nil {
+		log.Fatal("Failed to resolve instrument:", err)
+	}
+
+	intent := stocks.OrderIntent{
+		Ticker:     instrument.Ticker,
+		Type:       stocks.OrderTypeMarket,
+		Quantity:   quantity,
+		Amount:     *amount,
+		LimitPrice: *limit,
+		StopPrice:  *stop,
+		Price:      *price,
+	}
+	switch {
+	case *limit > 0 && *stop > 0:
+		intent.Type = stocks.OrderTypeStopLimit
+	case *limit > 0:
+		intent.Type = stocks.OrderTypeLimit
+	case *stop > 0:
+		intent.Type = stocks.OrderTypeStop
+	}
+	if intent.Type != stocks.OrderTypeMarket {
+		intent.TimeInForce = stocks.TimeInForce(strings.ToUpper(*tif))
+	}
+
+	preview, err := trader.Preview(ctx, intent)
+	if err != nil {
+		log.Fatal("Order rejected:", err)
+	}
+
+	// The preview prices a market order at the live price and sizes a cash
+	// amount order into a quantity
+	intent = preview.Intent
+	fmt.Printf("📝 %s %g %s (%s)\n", strings.ToUpper(side), abs(intent.Quantity), instrument.Name, instrument.Ticker)
+	switch intent.Type {
+	case stocks.OrderTypeMarket:
+		fmt.Printf("Market, live price %.2f %s\n", intent.Price, instrument.CurrencyCode)
+	case stocks.OrderTypeLimit:
+		fmt.Printf("Limit:       %.2f %s, %s\n", intent.LimitPrice, instrument.CurrencyCode, intent.TimeInForce)
+	case stocks.OrderTypeStop:
+		fmt.Printf("Stop:        %.2f %s, %s\n", intent.StopPrice, instrument.CurrencyCode, intent.TimeInForce)
+	case stocks.OrderTypeStopLimit:
+		fmt.Printf("Stop-limit:  stop %.2f, limit %.2f %s, %s\n", intent.StopPrice, intent.LimitPrice, instrument.CurrencyCode, intent.TimeInForce)
+	}
+	if instrument.MinTradeQuantity > 0 {
+		fmt.Printf("Limits:      minimum %g, maximum %g, %d decimal places\n",
+			instrument.MinTradeQuantity, instrument.MaxOpenQuantity, instrument.QuantityPrecision)
+	}
+	fmt.Printf("Value:       %.2f %s\n", preview.Value, preview.Currency)
+	fmt.Printf("Today:       %.2f %s submitted including this order\n", preview.DailyValue, preview.Currency)
+	fmt.Printf("Preview:     %s, valid until %s\n", preview.ID, preview.ExpiresAt.Format("15:04:05"))
+
+	if *dryRun {
+		fmt.Println("\nDry run, no order was placed")
+		return
+	}
+
+	fmt.Print("\nType yes to place this order: ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(answer) != "yes" {
+		fmt.Println("Order not placed")
+		return
+	}
+
+	order, err := trader.Place(ctx, preview)
+	if err != nil {
+		log.Fatal("Failed to place order:", err)
+	}
+	fmt.Printf("✅ Order %d placed, status %s\n", order.ID, order.Status)
+}
+
+func cancelOrder(audit *database.OrderAuditService, accountName string, orderID int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	catalogue, err := instruments.OpenFromEnv()
+	if err != nil {
+		log.Fatal("Failed to open instrument catalogue:", err)
+	}
+	trader, _ := newTrader(ctx, audit, accountName, catalogue)
+
+	if err := trader.Cancel(ctx, orderID); err != nil {
+		log.Fatal("Failed to cancel order:", err)
+	}
+	fmt.Printf("✅ Order %d cancelled\n", orderID)
+}
+
+func showAudit(audit *database.OrderAuditService, days int) {
+	entries, err := audit.GetOrderAudit(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		log.Fatal("Failed to get order audit log:", err)
+	}
+
+	fmt.Printf("📋 Order attempts (Last %d days)\n", days)
+	fmt.Printf("═══════════════════════════════════════\n")
+	if len(entries) == 0 {
+		fmt.Println("No order attempts")
+		return
+	}
+
+	for _, entry := range entries {
+		fmt.Printf("%s %-10s %-8s %-10s %-16s %10g %10.2f %s",
+			entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Account, entry.Action, entry.Outcome,
+			entry.Ticker, entry.Quantity, entry.Value, entry.Currency)
+		if entry.OrderID != 0 {
+			fmt.Printf(" order %d", entry.OrderID)
+		}
+		if entry.Reason != "" {
+			fmt.Printf(" (%s)", entry.Reason)
+		}
+		fmt.Println()
+	}
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
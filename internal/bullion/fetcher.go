@@ -1,40 +1,70 @@
 package bullion
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
-
-	"github.com/joho/godotenv"
+	"time"
 )
 
-func GetBullionPrices(bullion string, currency string) (*BullionPriceResponse, error) {
-	err := godotenv.Load()
-	if err != nil {
-		log.Printf("Info: No .env file found, using environment variables: %v", err)
+// Metals are the metal codes goldapi.io prices, with their names
+var Metals = map[string]string{
+	"XAU": "gold",
+	"XAG": "silver",
+	"XPT": "platinum",
+	"XPD": "palladium",
+}
+
+// Client fetches spot prices from goldapi.io
+type Client struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func NewClient(apiKey, baseURL string) *Client {
+	return &Client{
+		apiKey:  apiKey,
+		baseURL: baseURL,
+		client: &http.Client{
+			Timeout: 15 * time.Second,
+		},
 	}
+}
 
+func NewClientFromEnv() (*Client, error) {
 	apiKey := os.Getenv("BULLION_API_KEY")
-	baseURL := os.Getenv("BULLION_API_URL")
 	if apiKey == "" {
 		return nil, fmt.Errorf("BULLION_API_KEY environment variable is not set")
 	}
 
-	apiURL := baseURL + bullion + "/" + currency
+	return NewClient(apiKey, os.Getenv("BULLION_API_URL")), nil
+}
+
+func (c *Client) GetBullionPrices(ctx context.Context, bullion string, currency string) (*BullionPriceResponse, error) {
+	return c.get(ctx, bullion+"/"+currency)
+}
+
+// GetHistoricalPrice returns the closing price of a metal on a past day
+func (c *Client) GetHistoricalPrice(ctx context.Context, bullion string, currency string, date time.Time) (*BullionPriceResponse, error) {
+	return c.get(ctx, bullion+"/"+currency+"/"+date.Format("20060102"))
+}
+
+func (c *Client) get(ctx context.Context, path string) (*BullionPriceResponse, error) {
+	apiURL := c.baseURL + path
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Add("Accepts", "application/json")
-	req.Header.Add("x-access-token", apiKey)
+	req.Header.Add("x-access-token", c.apiKey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed:: %w", err)
 	}
@@ -1,9 +1,57 @@
 package conversion
 
-func USDToEUR(usd float64, rate float64) float64 {
-	return usd * rate
+import (
+	"context"
+	"fmt"
+	"investment-tracker/internal/fx"
+	"sync"
+	"time"
+)
+
+// Converter converts amounts between currencies using reference rates
+type Converter struct {
+	rates *fx.Service
+}
+
+func NewConverter(rates *fx.Service) *Converter {
+	return &Converter{
+		rates: rates,
+	}
+}
+
+var (
+	defaultOnce      sync.Once
+	defaultConverter *Converter
+)
+
+// Default returns the process-wide converter backed by the ECB feed. It is
+// built on first use, after main has loaded .env, so ECB_BASE_URL applies
+func Default() *Converter {
+	defaultOnce.Do(func() {
+		defaultConverter = NewConverter(fx.NewServiceFromEnv())
+	})
+	return defaultConverter
+}
+
+// Rate returns how many units of "to" one unit of "from" was worth on asOf
+func (c *Converter) Rate(ctx context.Context, from, to string, asOf time.Time) (float64, error) {
+	rate, err := c.rates.Rate(ctx, from, to, asOf)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get %s/%s rate: %w", from, to, err)
+	}
+	return rate, nil
+}
+
+// Convert converts an amount between currencies at the rate valid on asOf
+func (c *Converter) Convert(ctx context.Context, amount float64, from, to string, asOf time.Time) (float64, error) {
+	rate, err := c.Rate(ctx, from, to, asOf)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
 }
 
-func USDToBGN(usd float64, rate float64) float64 {
-	return usd * rate
+// Convert converts an amount using the default converter
+func Convert(ctx context.Context, amount float64, from, to string, asOf time.Time) (float64, error) {
+	return Default().Convert(ctx, amount, from, to, asOf)
 }
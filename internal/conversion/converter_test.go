@@ -0,0 +1,30 @@
+package conversion
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDefaultReadsECBBaseURLOnFirstUse(t *testing.T) {
+	today := time.Now().UTC().Format("2006-01-02")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref"><Cube><Cube time="%s"><Cube currency="USD" rate="1.25"/></Cube></Cube></gesmes:Envelope>`, today)
+	}))
+	defer server.Close()
+
+	// Set after package initialisation, as main does when it loads .env
+	t.Setenv("ECB_BASE_URL", server.URL)
+
+	amount, err := Convert(context.Background(), 10, "EUR", "USD", time.Now())
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if math.Abs(amount-12.5) > 1e-9 {
+		t.Errorf("Convert(10 EUR) = %v USD, want 12.5 from the stand-in feed", amount)
+	}
+}
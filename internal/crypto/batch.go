@@ -0,0 +1,60 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+)
+
+// Quotes is the result of a batch price request: the prices found and an
+// error for every symbol that could not be priced
+type Quotes struct {
+	Prices map[string]*Quote
+	Errors map[string]error
+}
+
+func NewQuotes() *Quotes {
+	return &Quotes{
+		Prices: make(map[string]*Quote),
+		Errors: make(map[string]error),
+	}
+}
+
+// BatchPriceProvider fetches the prices of many coins in a single request.
+// The returned error is reserved for failures of the whole request
+type BatchPriceProvider interface {
+	PriceProvider
+	GetPrices(ctx context.Context, symbols []string, currency string) (*Quotes, error)
+}
+
+// GetPrices prices every symbol with a provider, batching when it supports it
+func GetPrices(ctx context.Context, provider PriceProvider, symbols []string, currency string) (*Quotes, error) {
+	if batch, ok := provider.(BatchPriceProvider); ok {
+		return batch.GetPrices(ctx, symbols, currency)
+	}
+
+	quotes := NewQuotes()
+	for _, symbol := range symbols {
+		quote, err := provider.GetPrice(ctx, symbol, currency)
+		if err != nil {
+			quotes.Errors[symbol] = err
+			continue
+		}
+		quotes.Prices[symbol] = quote
+	}
+
+	return quotes, nil
+}
+
+// quoteFromBatch picks one symbol out of a batch result
+func quoteFromBatch(quotes *Quotes, err error, symbol string) (*Quote, error) {
+	if err != nil {
+		return nil, err
+	}
+	if quote, ok := quotes.Prices[symbol]; ok {
+		return quote, nil
+	}
+	if err, ok := quotes.Errors[symbol]; ok {
+		return nil, err
+	}
+	return nil, fmt.Errorf("coin %s %w", symbol, ErrNotFound)
+}
@@ -0,0 +1,90 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultBinanceURL = "https://api.binance.com"
+
+// binanceCurrencies are the fiat currencies with spot pairs for most coins
+var binanceCurrencies = map[string]bool{
+	"USD": true, "EUR": true, "TRY": true, "BRL": true,
+}
+
+// Binance prices coins with the public Binance spot ticker, no API key needed.
+// USD prices use the USDT pair, which tracks the dollar closely enough for valuation
+type Binance struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewBinance(baseURL string) *Binance {
+	if baseURL == "" {
+		baseURL = DefaultBinanceURL
+	}
+
+	return &Binance{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  newHTTPClient(),
+	}
+}
+
+func (b *Binance) Name() string {
+	return "binance"
+}
+
+func (b *Binance) Quotes(currency string) bool {
+	return binanceCurrencies[currency]
+}
+
+func (b *Binance) GetPrice(ctx context.Context, symbol, currency string) (*Quote, error) {
+	quoteAsset := currency
+	if currency == "USD" {
+		quoteAsset = "USDT"
+	}
+
+	if symbol == quoteAsset {
+		return &Quote{Symbol: symbol, Price: 1, Currency: currency, Source: b.Name(), Timestamp: time.Now()}, nil
+	}
+
+	query := url.Values{}
+	query.Set("symbol", symbol+quoteAsset)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+"/api/v3/ticker/price?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var ticker struct {
+		Symbol string `json:"symbol"`
+		Price  string `json:"price"`
+	}
+	if err := doJSON(b.client, req, "Binance", &ticker); err != nil {
+		// Binance answers a pair it does not list with error code -1121
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.statusCode == http.StatusBadRequest && strings.Contains(apiErr.body, "-1121") {
+			return nil, fmt.Errorf("pair %s%s %w: %v", symbol, quoteAsset, ErrNotFound, err)
+		}
+		return nil, err
+	}
+
+	price, err := strconv.ParseFloat(ticker.Price, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid price format %q: %w", ticker.Price, err)
+	}
+
+	return &Quote{
+		Symbol:    symbol,
+		Price:     price,
+		Currency:  currency,
+		Source:    b.Name(),
+		Timestamp: time.Now(),
+	}, nil
+}
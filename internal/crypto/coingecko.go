@@ -0,0 +1,193 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultCoinGeckoURL = "https://api.coingecko.com/api/v3"
+
+// knownCoinGeckoIDs resolves common symbols without a search request,
+// since many CoinGecko coins share a ticker symbol
+var knownCoinGeckoIDs = map[string]string{
+	"BTC":  "bitcoin",
+	"ETH":  "ethereum",
+	"SOL":  "solana",
+	"XRP":  "ripple",
+	"LTC":  "litecoin",
+	"DOGE": "dogecoin",
+	"ADA":  "cardano",
+	"BGB":  "bitget-token",
+	"AVAX": "avalanche-2",
+	"XLM":  "stellar",
+	"HYPE": "hyperliquid",
+	"USDC": "usd-coin",
+	"USDT": "tether",
+	"TRX":  "tron",
+}
+
+// coinGeckoCurrencies are the fiat vs_currencies CoinGecko quotes; the lev is not among them
+var coinGeckoCurrencies = map[string]bool{
+	"USD": true, "EUR": true, "GBP": true, "CHF": true, "JPY": true, "CAD": true,
+	"AUD": true, "NZD": true, "SEK": true, "NOK": true, "DKK": true, "PLN": true,
+	"CZK": true, "HUF": true, "TRY": true, "CNY": true, "HKD": true, "SGD": true,
+	"KRW": true, "INR": true, "BRL": true, "MXN": true, "ZAR": true, "ILS": true,
+}
+
+// CoinGecko prices coins with the CoinGecko API; the API key is optional
+type CoinGecko struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+
+	mu  sync.Mutex
+	ids map[string]string
+}
+
+func NewCoinGecko(apiKey, baseURL string) *CoinGecko {
+	if baseURL == "" {
+		baseURL = DefaultCoinGeckoURL
+	}
+
+	ids := make(map[string]string, len(knownCoinGeckoIDs))
+	for symbol, id := range knownCoinGeckoIDs {
+		ids[symbol] = id
+	}
+
+	return &CoinGecko{
+		apiKey:  apiKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  newHTTPClient(),
+		ids:     ids,
+	}
+}
+
+func (c *CoinGecko) Name() string {
+	return "coingecko"
+}
+
+func (c *CoinGecko) Quotes(currency string) bool {
+	return coinGeckoCurrencies[currency]
+}
+
+func (c *CoinGecko) GetPrice(ctx context.Context, symbol, currency string) (*Quote, error) {
+	quotes, err := c.GetPrices(ctx, []string{symbol}, currency)
+	return quoteFromBatch(quotes, err, symbol)
+}
+
+// GetPrices fetches every resolvable symbol with one simple price request
+func (c *CoinGecko) GetPrices(ctx context.Context, symbols []string, currency string) (*Quotes, error) {
+	quotes := NewQuotes()
+
+	symbolsByID := make(map[string]string)
+	var ids []string
+	for _, symbol := range symbols {
+		id, err := c.coinID(ctx, symbol)
+		if err != nil {
+			quotes.Errors[symbol] = err
+			continue
+		}
+		symbolsByID[id] = symbol
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		return quotes, nil
+	}
+
+	vsCurrency := strings.ToLower(currency)
+	query := url.Values{}
+	query.Set("ids", strings.Join(ids, ","))
+	query.Set("vs_currencies", vsCurrency)
+	query.Set("include_last_updated_at", "true")
+
+	var result map[string]map[string]float64
+	if err := c.get(ctx, "/simple/price?"+query.Encode(), &result); err != nil {
+		return nil, err
+	}
+
+	for id, symbol := range symbolsByID {
+		price, ok := result[id][vsCurrency]
+		if !ok {
+			quotes.Errors[symbol] = fmt.Errorf("%s price for %s %w", currency, symbol, ErrNotFound)
+			continue
+		}
+
+		timestamp := time.Now()
+		if updated, ok := result[id]["last_updated_at"]; ok {
+			timestamp = time.Unix(int64(updated), 0)
+		}
+
+		quotes.Prices[symbol] = &Quote{
+			Symbol:    symbol,
+			Price:     price,
+			Currency:  currency,
+			Source:    c.Name(),
+			Timestamp: timestamp,
+		}
+	}
+
+	return quotes, nil
+}
+
+// coinID maps a ticker symbol to a CoinGecko coin id, preferring the
+// highest market cap coin when several share the symbol
+func (c *CoinGecko) coinID(ctx context.Context, symbol string) (string, error) {
+	c.mu.Lock()
+	id, ok := c.ids[symbol]
+	c.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	var result struct {
+		Coins []struct {
+			ID            string `json:"id"`
+			Symbol        string `json:"symbol"`
+			MarketCapRank int    `json:"market_cap_rank"`
+		} `json:"coins"`
+	}
+	if err := c.get(ctx, "/search?query="+url.QueryEscape(symbol), &result); err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", symbol, err)
+	}
+
+	bestRank := 0
+	for _, coin := range result.Coins {
+		if !strings.EqualFold(coin.Symbol, symbol) || coin.MarketCapRank == 0 {
+			continue
+		}
+		if id == "" || coin.MarketCapRank < bestRank {
+			id = coin.ID
+			bestRank = coin.MarketCapRank
+		}
+	}
+
+	if id == "" {
+		return "", fmt.Errorf("coin %s %w", symbol, ErrNotFound)
+	}
+
+	c.mu.Lock()
+	c.ids[symbol] = id
+	c.mu.Unlock()
+
+	return id, nil
+}
+
+func (c *CoinGecko) get(ctx context.Context, endpoint string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Add("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Add("x-cg-demo-api-key", c.apiKey)
+	}
+
+	return doJSON(c.client, req, "CoinGecko", out)
+}
@@ -0,0 +1,101 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const DefaultCoinMarketCapURL = "https://pro-api.coinmarketcap.com"
+
+// CoinMarketCap prices coins with the CoinMarketCap Pro API
+type CoinMarketCap struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+type cmcQuotesResponse struct {
+	Status struct {
+		ErrorCode    int    `json:"error_code"`
+		ErrorMessage string `json:"error_message"`
+	} `json:"status"`
+	Data map[string]struct {
+		Symbol string `json:"symbol"`
+		Quote  map[string]struct {
+			Price       float64   `json:"price"`
+			LastUpdated time.Time `json:"last_updated"`
+		} `json:"quote"`
+	} `json:"data"`
+}
+
+func NewCoinMarketCap(apiKey, baseURL string) *CoinMarketCap {
+	if baseURL == "" {
+		baseURL = DefaultCoinMarketCapURL
+	}
+
+	return &CoinMarketCap{
+		apiKey:  apiKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  newHTTPClient(),
+	}
+}
+
+func (c *CoinMarketCap) Name() string {
+	return "coinmarketcap"
+}
+
+func (c *CoinMarketCap) GetPrice(ctx context.Context, symbol, currency string) (*Quote, error) {
+	quotes, err := c.GetPrices(ctx, []string{symbol}, currency)
+	return quoteFromBatch(quotes, err, symbol)
+}
+
+// GetPrices fetches every symbol with one quotes request, so a full
+// portfolio costs a single call and a single credit
+func (c *CoinMarketCap) GetPrices(ctx context.Context, symbols []string, currency string) (*Quotes, error) {
+	query := url.Values{}
+	query.Set("symbol", strings.Join(symbols, ","))
+	query.Set("convert", currency)
+	query.Set("skip_invalid", "true")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v1/cryptocurrency/quotes/latest?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Accepts", "application/json")
+	req.Header.Add("X-CMC_PRO_API_KEY", c.apiKey)
+
+	var result cmcQuotesResponse
+	if err := doJSON(c.client, req, "CoinMarketCap", &result); err != nil {
+		return nil, err
+	}
+
+	quotes := NewQuotes()
+	for _, symbol := range symbols {
+		coin, ok := result.Data[symbol]
+		if !ok {
+			quotes.Errors[symbol] = fmt.Errorf("coin %s %w", symbol, ErrNotFound)
+			continue
+		}
+
+		quote, ok := coin.Quote[currency]
+		if !ok {
+			quotes.Errors[symbol] = fmt.Errorf("%s price for %s %w", currency, symbol, ErrNotFound)
+			continue
+		}
+
+		quotes.Prices[symbol] = &Quote{
+			Symbol:    symbol,
+			Price:     quote.Price,
+			Currency:  currency,
+			Source:    c.Name(),
+			Timestamp: quote.LastUpdated,
+		}
+	}
+
+	return quotes, nil
+}
@@ -0,0 +1,48 @@
+package crypto
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// DefaultProviders is the fallback order used when CRYPTO_PROVIDERS is unset
+const DefaultProviders = "coinmarketcap,coingecko,binance"
+
+// NewChainFromEnv builds the provider chain from CRYPTO_PROVIDERS and the
+// per-provider API key and base URL variables
+func NewChainFromEnv() (*Chain, error) {
+	names := os.Getenv("CRYPTO_PROVIDERS")
+	if names == "" {
+		names = DefaultProviders
+	}
+
+	var providers []PriceProvider
+	for _, name := range strings.Split(strings.ToLower(names), ",") {
+		name = strings.TrimSpace(name)
+
+		switch name {
+		case "coinmarketcap":
+			apiKey := os.Getenv("CMC_API_KEY")
+			if apiKey == "" {
+				log.Printf("Info: CMC_API_KEY is not set, skipping coinmarketcap")
+				continue
+			}
+			providers = append(providers, NewCoinMarketCap(apiKey, os.Getenv("CMC_BASE_URL")))
+		case "coingecko":
+			providers = append(providers, NewCoinGecko(os.Getenv("COINGECKO_API_KEY"), os.Getenv("COINGECKO_BASE_URL")))
+		case "binance":
+			providers = append(providers, NewBinance(os.Getenv("BINANCE_BASE_URL")))
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown crypto price provider: %s", name)
+		}
+	}
+
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no crypto price providers configured")
+	}
+
+	return NewChain(providers...), nil
+}
@@ -0,0 +1,130 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HistoricalPriceProvider fetches the price of a coin on a past day
+type HistoricalPriceProvider interface {
+	PriceProvider
+	GetHistoricalPrice(ctx context.Context, symbol, currency string, date time.Time) (*Quote, error)
+}
+
+// GetHistoricalPrice asks each provider that keeps history, in order
+func (c *Chain) GetHistoricalPrice(ctx context.Context, symbol, currency string, date time.Time) (*Quote, error) {
+	var errs []error
+	for _, provider := range c.providers {
+		historical, ok := provider.(HistoricalPriceProvider)
+		if !ok {
+			continue
+		}
+
+		quote, err := historical.GetHistoricalPrice(ctx, symbol, currency, date)
+		if err == nil {
+			return quote, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no configured crypto provider offers historical prices")
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// GetHistoricalPrice returns the last daily quote on or before the end of date.
+// The historical endpoint needs a paid CoinMarketCap plan
+func (c *CoinMarketCap) GetHistoricalPrice(ctx context.Context, symbol, currency string, date time.Time) (*Quote, error) {
+	query := url.Values{}
+	query.Set("symbol", symbol)
+	query.Set("convert", currency)
+	query.Set("interval", "daily")
+	query.Set("count", "1")
+	query.Set("time_end", endOfDay(date).Format(time.RFC3339))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v2/cryptocurrency/quotes/historical?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Accepts", "application/json")
+	req.Header.Add("X-CMC_PRO_API_KEY", c.apiKey)
+
+	var result struct {
+		Data map[string][]struct {
+			Quotes []struct {
+				Timestamp time.Time `json:"timestamp"`
+				Quote     map[string]struct {
+					Price float64 `json:"price"`
+				} `json:"quote"`
+			} `json:"quotes"`
+		} `json:"data"`
+	}
+	if err := doJSON(c.client, req, "CoinMarketCap", &result); err != nil {
+		return nil, err
+	}
+
+	for _, coin := range result.Data[symbol] {
+		for _, quote := range coin.Quotes {
+			if price, ok := quote.Quote[currency]; ok {
+				return &Quote{
+					Symbol:    symbol,
+					Price:     price.Price,
+					Currency:  currency,
+					Source:    c.Name(),
+					Timestamp: quote.Timestamp,
+				}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no %s price for %s on %s", currency, symbol, date.Format("2006-01-02"))
+}
+
+// GetHistoricalPrice returns CoinGecko's daily snapshot price for date
+func (c *CoinGecko) GetHistoricalPrice(ctx context.Context, symbol, currency string, date time.Time) (*Quote, error) {
+	id, err := c.coinID(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("date", date.Format("02-01-2006"))
+	query.Set("localization", "false")
+
+	var result struct {
+		MarketData struct {
+			CurrentPrice map[string]float64 `json:"current_price"`
+		} `json:"market_data"`
+	}
+	if err := c.get(ctx, "/coins/"+url.PathEscape(id)+"/history?"+query.Encode(), &result); err != nil {
+		return nil, err
+	}
+
+	price, ok := result.MarketData.CurrentPrice[strings.ToLower(currency)]
+	if !ok {
+		return nil, fmt.Errorf("no %s price for %s on %s", currency, symbol, date.Format("2006-01-02"))
+	}
+
+	return &Quote{
+		Symbol:    symbol,
+		Price:     price,
+		Currency:  currency,
+		Source:    c.Name(),
+		Timestamp: date,
+	}, nil
+}
+
+func endOfDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 0, time.UTC)
+}
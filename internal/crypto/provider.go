@@ -0,0 +1,215 @@
+package crypto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is wrapped by the error of a provider that answered without a
+// price for a coin, as opposed to one that could not be asked
+var ErrNotFound = errors.New("not found")
+
+// Quote is the price of one coin as reported by a provider
+type Quote struct {
+	Symbol    string
+	Price     float64
+	Currency  string
+	Source    string
+	Timestamp time.Time
+}
+
+// PriceProvider fetches the current price of a coin in a fiat currency
+type PriceProvider interface {
+	Name() string
+	GetPrice(ctx context.Context, symbol, currency string) (*Quote, error)
+}
+
+// CurrencyQuoter is implemented by providers that only quote some fiat currencies
+type CurrencyQuoter interface {
+	Quotes(currency string) bool
+}
+
+// RateFunc returns how many units of "to" one unit of "from" is worth
+type RateFunc func(ctx context.Context, from, to string) (float64, error)
+
+// Chain tries its providers in order and returns the first price found
+type Chain struct {
+	providers []PriceProvider
+	rate      RateFunc
+}
+
+func NewChain(providers ...PriceProvider) *Chain {
+	return &Chain{
+		providers: providers,
+	}
+}
+
+// ConvertWith lets providers that do not quote a currency price in USD,
+// converted at the given rate, instead of being skipped
+func (c *Chain) ConvertWith(rate RateFunc) {
+	c.rate = rate
+}
+
+func (c *Chain) Name() string {
+	return strings.Join(c.Sources(), "/")
+}
+
+// Sources returns the provider names in fallback order
+func (c *Chain) Sources() []string {
+	names := make([]string, 0, len(c.providers))
+	for _, provider := range c.providers {
+		names = append(names, provider.Name())
+	}
+	return names
+}
+
+func (c *Chain) GetPrice(ctx context.Context, symbol, currency string) (*Quote, error) {
+	if len(c.providers) == 0 {
+		return nil, fmt.Errorf("no crypto price providers configured")
+	}
+
+	var errs []error
+	for _, provider := range c.providers {
+		quote, err := c.getPrice(ctx, provider, symbol, currency)
+		if err == nil {
+			return quote, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		log.Printf("Warning: %s could not price %s, trying next provider: %v", provider.Name(), symbol, err)
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// GetPrices prices all symbols with the first provider and passes whatever
+// it could not price on to the next one
+func (c *Chain) GetPrices(ctx context.Context, symbols []string, currency string) (*Quotes, error) {
+	if len(c.providers) == 0 {
+		return nil, fmt.Errorf("no crypto price providers configured")
+	}
+
+	result := NewQuotes()
+	remaining := symbols
+	errs := make(map[string][]error)
+
+	for _, provider := range c.providers {
+		// Falling back is pointless once the deadline has passed
+		if len(remaining) == 0 || ctx.Err() != nil {
+			break
+		}
+
+		quotes, err := c.getPrices(ctx, provider, remaining, currency)
+		if err != nil {
+			log.Printf("Warning: %s could not price %v, trying next provider: %v", provider.Name(), remaining, err)
+			for _, symbol := range remaining {
+				errs[symbol] = append(errs[symbol], fmt.Errorf("%s: %w", provider.Name(), err))
+			}
+			continue
+		}
+
+		var missing []string
+		for _, symbol := range remaining {
+			if quote, ok := quotes.Prices[symbol]; ok {
+				result.Prices[symbol] = quote
+				continue
+			}
+			if err, ok := quotes.Errors[symbol]; ok {
+				errs[symbol] = append(errs[symbol], fmt.Errorf("%s: %w", provider.Name(), err))
+			}
+			missing = append(missing, symbol)
+		}
+		remaining = missing
+	}
+
+	for _, symbol := range remaining {
+		if ctx.Err() != nil && len(errs[symbol]) == 0 {
+			errs[symbol] = append(errs[symbol], ctx.Err())
+		}
+		result.Errors[symbol] = errors.Join(errs[symbol]...)
+	}
+
+	return result, nil
+}
+
+// quoteCurrency returns the currency to ask a provider for: the requested
+// one when it quotes it, otherwise USD to be converted
+func (c *Chain) quoteCurrency(provider PriceProvider, currency string) string {
+	if quoter, ok := provider.(CurrencyQuoter); ok && c.rate != nil && !quoter.Quotes(currency) {
+		return "USD"
+	}
+	return currency
+}
+
+func (c *Chain) getPrice(ctx context.Context, provider PriceProvider, symbol, currency string) (*Quote, error) {
+	quotes, err := c.getPrices(ctx, provider, []string{symbol}, currency)
+	return quoteFromBatch(quotes, err, symbol)
+}
+
+// getPrices prices symbols with one provider, converting from USD when the
+// provider does not quote the requested currency
+func (c *Chain) getPrices(ctx context.Context, provider PriceProvider, symbols []string, currency string) (*Quotes, error) {
+	quoteCurrency := c.quoteCurrency(provider, currency)
+	quotes, err := GetPrices(ctx, provider, symbols, quoteCurrency)
+	if err != nil || quoteCurrency == currency || len(quotes.Prices) == 0 {
+		return quotes, err
+	}
+
+	rate, err := c.rate(ctx, quoteCurrency, currency)
+	if err != nil {
+		return nil, fmt.Errorf("no %s price and no %s rate to convert it: %w", currency, quoteCurrency, err)
+	}
+
+	for _, quote := range quotes.Prices {
+		quote.Price *= rate
+		quote.Currency = currency
+	}
+	return quotes, nil
+}
+
+// doJSON sends a request and decodes a successful JSON response into out
+func doJSON(client *http.Client, req *http.Request, api string, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &apiError{api: api, statusCode: resp.StatusCode, body: string(body)}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
+
+// apiError is a non-success response from a price API
+type apiError struct {
+	api        string
+	statusCode int
+	body       string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s API error: HTTP %d, body: %s", e.api, e.statusCode, e.body)
+}
+
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: 15 * time.Second,
+	}
+}
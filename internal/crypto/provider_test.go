@@ -0,0 +1,299 @@
+package crypto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// standIn serves fixed responses by path and counts the requests it gets
+type standIn struct {
+	*httptest.Server
+	requests atomic.Int32
+}
+
+func newStandIn(t *testing.T, handler http.HandlerFunc) *standIn {
+	t.Helper()
+
+	s := &standIn{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// outOfCredits answers like CoinMarketCap once the monthly credits are spent
+func outOfCredits(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusPaymentRequired)
+	writeJSON(w, map[string]interface{}{
+		"status": map[string]interface{}{"error_code": 1008, "error_message": "monthly credit limit reached"},
+	})
+}
+
+// coinGeckoStandIn prices the given coins, quoted per vs currency
+func coinGeckoStandIn(prices map[string]map[string]float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/simple/price":
+			result := make(map[string]map[string]float64)
+			vs := r.URL.Query().Get("vs_currencies")
+			for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+				if price, ok := prices[id][vs]; ok {
+					result[id] = map[string]float64{vs: price, "last_updated_at": 1700000000}
+				}
+			}
+			writeJSON(w, result)
+		case "/search":
+			writeJSON(w, map[string]interface{}{"coins": []interface{}{}})
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+// binanceStandIn quotes the given trading pairs
+func binanceStandIn(pairs map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		symbol := r.URL.Query().Get("symbol")
+		price, ok := pairs[symbol]
+		if r.URL.Path != "/api/v3/ticker/price" || !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]interface{}{"code": -1121, "msg": "Invalid symbol."})
+			return
+		}
+		writeJSON(w, map[string]string{"symbol": symbol, "price": price})
+	}
+}
+
+func TestChainFallsBackPerSymbol(t *testing.T) {
+	cmc := newStandIn(t, outOfCredits)
+	gecko := newStandIn(t, coinGeckoStandIn(map[string]map[string]float64{
+		"bitcoin": {"usd": 60000},
+	}))
+	binance := newStandIn(t, binanceStandIn(map[string]string{
+		"ETHUSDT": "3000.50",
+	}))
+
+	t.Setenv("CRYPTO_PROVIDERS", "coinmarketcap,coingecko,binance")
+	t.Setenv("CMC_API_KEY", "test")
+	t.Setenv("CMC_BASE_URL", cmc.URL)
+	t.Setenv("COINGECKO_BASE_URL", gecko.URL)
+	t.Setenv("BINANCE_BASE_URL", binance.URL)
+
+	chain, err := NewChainFromEnv()
+	if err != nil {
+		t.Fatalf("NewChainFromEnv failed: %v", err)
+	}
+	if got, want := chain.Name(), "coinmarketcap/coingecko/binance"; got != want {
+		t.Errorf("Name() = %q, want %q", got, want)
+	}
+
+	quotes, err := chain.GetPrices(context.Background(), []string{"BTC", "ETH", "NOPE"}, "USD")
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+
+	tests := []struct {
+		symbol string
+		price  float64
+		source string
+	}{
+		{"BTC", 60000, "coingecko"},
+		{"ETH", 3000.50, "binance"},
+	}
+	for _, tt := range tests {
+		quote, ok := quotes.Prices[tt.symbol]
+		if !ok {
+			t.Errorf("%s not priced: %v", tt.symbol, quotes.Errors[tt.symbol])
+			continue
+		}
+		if quote.Price != tt.price || quote.Source != tt.source {
+			t.Errorf("%s = %v from %s, want %v from %s", tt.symbol, quote.Price, quote.Source, tt.price, tt.source)
+		}
+	}
+
+	err = quotes.Errors["NOPE"]
+	if err == nil {
+		t.Fatal("NOPE priced, want an error from every provider")
+	}
+	for _, provider := range []string{"coinmarketcap", "coingecko", "binance"} {
+		if !strings.Contains(err.Error(), provider) {
+			t.Errorf("NOPE error %q does not mention %s", err, provider)
+		}
+	}
+
+	// The batch providers are asked once for everything they were given
+	if cmc.requests.Load() != 1 {
+		t.Errorf("coinmarketcap got %d requests, want 1", cmc.requests.Load())
+	}
+}
+
+func TestChainStopsAtFirstProvider(t *testing.T) {
+	cmc := newStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"data": map[string]interface{}{
+				"BTC": map[string]interface{}{
+					"symbol": "BTC",
+					"quote": map[string]interface{}{
+						"USD": map[string]interface{}{"price": 61000, "last_updated": "2024-01-02T03:04:05Z"},
+					},
+				},
+			},
+		})
+	})
+	gecko := newStandIn(t, coinGeckoStandIn(nil))
+
+	chain := NewChain(NewCoinMarketCap("test", cmc.URL), NewCoinGecko("", gecko.URL))
+	quote, err := chain.GetPrice(context.Background(), "BTC", "USD")
+	if err != nil {
+		t.Fatalf("GetPrice failed: %v", err)
+	}
+	if quote.Price != 61000 || quote.Source != "coinmarketcap" {
+		t.Errorf("BTC = %v from %s, want 61000 from coinmarketcap", quote.Price, quote.Source)
+	}
+	if gecko.requests.Load() != 0 {
+		t.Errorf("coingecko got %d requests, want none once coinmarketcap answered", gecko.requests.Load())
+	}
+}
+
+func TestChainReportsEveryFailure(t *testing.T) {
+	cmc := newStandIn(t, outOfCredits)
+	binance := newStandIn(t, binanceStandIn(nil))
+
+	chain := NewChain(NewCoinMarketCap("test", cmc.URL), NewBinance(binance.URL))
+	_, err := chain.GetPrice(context.Background(), "BTC", "USD")
+	if err == nil {
+		t.Fatal("GetPrice succeeded, want an error when every provider fails")
+	}
+	if !strings.Contains(err.Error(), "HTTP 402") || !strings.Contains(err.Error(), "Invalid symbol") {
+		t.Errorf("error %q does not carry both provider failures", err)
+	}
+}
+
+func TestChainConvertsForProvidersWithoutTheCurrency(t *testing.T) {
+	var convert atomic.Value
+	cmc := newStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		convert.Store(r.URL.Query().Get("convert"))
+		outOfCredits(w, r)
+	})
+	gecko := newStandIn(t, coinGeckoStandIn(map[string]map[string]float64{
+		"bitcoin": {"usd": 60000},
+	}))
+	binance := newStandIn(t, binanceStandIn(map[string]string{
+		"ETHUSDT": "3000",
+	}))
+
+	chain := NewChain(NewCoinMarketCap("test", cmc.URL), NewCoinGecko("", gecko.URL), NewBinance(binance.URL))
+	chain.ConvertWith(func(ctx context.Context, from, to string) (float64, error) {
+		if from != "USD" || to != "BGN" {
+			t.Errorf("asked for a %s/%s rate, want USD/BGN", from, to)
+		}
+		return 1.8, nil
+	})
+
+	quotes, err := chain.GetPrices(context.Background(), []string{"BTC", "ETH"}, "BGN")
+	if err != nil {
+		t.Fatalf("GetPrices failed: %v", err)
+	}
+
+	// CoinMarketCap quotes the lev itself, so it is asked for it directly
+	if got := convert.Load(); got != "BGN" {
+		t.Errorf("coinmarketcap asked to convert to %v, want BGN", got)
+	}
+
+	tests := []struct {
+		symbol string
+		price  float64
+		source string
+	}{
+		{"BTC", 108000, "coingecko"},
+		{"ETH", 5400, "binance"},
+	}
+	for _, tt := range tests {
+		quote, ok := quotes.Prices[tt.symbol]
+		if !ok {
+			t.Errorf("%s not priced: %v", tt.symbol, quotes.Errors[tt.symbol])
+			continue
+		}
+		if quote.Price != tt.price || quote.Currency != "BGN" || quote.Source != tt.source {
+			t.Errorf("%s = %v %s from %s, want %v BGN from %s", tt.symbol, quote.Price, quote.Currency, quote.Source, tt.price, tt.source)
+		}
+	}
+}
+
+func TestChainWithoutRateFailsOverToNextProvider(t *testing.T) {
+	gecko := newStandIn(t, coinGeckoStandIn(map[string]map[string]float64{
+		"bitcoin": {"usd": 60000},
+	}))
+	binance := newStandIn(t, binanceStandIn(map[string]string{
+		"BTCUSDT": "60000",
+	}))
+
+	chain := NewChain(NewCoinGecko("", gecko.URL), NewBinance(binance.URL))
+	chain.ConvertWith(func(ctx context.Context, from, to string) (float64, error) {
+		return 0, errors.New("ECB unavailable")
+	})
+
+	_, err := chain.GetPrice(context.Background(), "BTC", "BGN")
+	if err == nil || !strings.Contains(err.Error(), "ECB unavailable") {
+		t.Errorf("error = %v, want the missing rate to be reported", err)
+	}
+}
+
+func TestChainTellsUnknownSymbolsFromFailures(t *testing.T) {
+	cmc := newStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"data": map[string]interface{}{}})
+	})
+	gecko := newStandIn(t, coinGeckoStandIn(nil))
+	binance := newStandIn(t, binanceStandIn(nil))
+	down := newStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+
+	tests := []struct {
+		name      string
+		providers []PriceProvider
+		notFound  bool
+	}{
+		{"every provider answers", []PriceProvider{NewCoinMarketCap("test", cmc.URL), NewCoinGecko("", gecko.URL), NewBinance(binance.URL)}, true},
+		{"one provider is down", []PriceProvider{NewCoinMarketCap("test", cmc.URL), NewBinance(down.URL)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotes, err := NewChain(tt.providers...).GetPrices(context.Background(), []string{"NOPE"}, "USD")
+			if err != nil {
+				t.Fatalf("GetPrices failed: %v", err)
+			}
+
+			joined, ok := quotes.Errors["NOPE"].(interface{ Unwrap() []error })
+			if !ok {
+				t.Fatalf("NOPE error %v is not one per provider", quotes.Errors["NOPE"])
+			}
+			errs := joined.Unwrap()
+			if len(errs) != len(tt.providers) {
+				t.Fatalf("got %d provider errors, want %d", len(errs), len(tt.providers))
+			}
+
+			notFound := true
+			for _, err := range errs {
+				notFound = notFound && errors.Is(err, ErrNotFound)
+			}
+			if notFound != tt.notFound {
+				t.Errorf("every provider not found = %v, want %v: %v", notFound, tt.notFound, quotes.Errors["NOPE"])
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"investment-tracker/internal/stocks"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ActivityService stores Trading212 account activity imported from history exports
+type ActivityService struct {
+	db         *MongoDB
+	collection *mongo.Collection
+}
+
+func NewActivityService(db *MongoDB) *ActivityService {
+	as := &ActivityService{
+		db:         db,
+		collection: db.GetCollection("trading212_activity"),
+	}
+
+	// Every import upserts by key, which would otherwise scan the collection per row
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := as.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "key", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Warning: Could not create the Trading212 activity key index: %v", err)
+	}
+
+	return as
+}
+
+// SaveExportRows upserts the export rows of an account, so re-exporting an
+// overlapping range never duplicates activity, and returns how many rows were new
+func (as *ActivityService) SaveExportRows(account string, rows []stocks.ExportRow) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	var models []mongo.WriteModel
+	for _, row := range rows {
+		activity := Trading212Activity{
+			Key:                    activityKey(account, row),
+			Account:                account,
+			Action:                 row.Action,
+			Time:                   row.Time,
+			ISIN:                   row.ISIN,
+			Ticker:                 row.Ticker,
+			Name:                   row.Name,
+			Shares:                 row.Shares,
+			PricePerShare:          row.PricePerShare,
+			PriceCurrency:          row.PriceCurrency,
+			ExchangeRate:           row.ExchangeRate,
+			Result:                 row.Result,
+			ResultCurrency:         row.ResultCurrency,
+			Total:                  row.Total,
+			TotalCurrency:          row.TotalCurrency,
+			WithholdingTax:         row.WithholdingTax,
+			WithholdingTaxCurrency: row.WithholdingTaxCurrency,
+			ConversionFee:          row.ConversionFee,
+			ConversionFeeCurrency:  row.ConversionFeeCurrency,
+			Notes:                  row.Notes,
+			ExternalID:             row.ID,
+			ImportedAt:             now,
+		}
+
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"key": activity.Key}).
+			SetUpdate(bson.M{"$set": activity}).
+			SetUpsert(true))
+	}
+
+	result, err := as.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, fmt.Errorf("failed to save Trading212 activity: %w", err)
+	}
+
+	log.Printf("Imported %d %s activity rows, %d new", len(rows), account, result.UpsertedCount)
+	return int(result.UpsertedCount), nil
+}
+
+// GetActivity returns the activity between two times, oldest first
+func (as *ActivityService) GetActivity(from, to time.Time) ([]Trading212Activity, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{"time": bson.M{"$gte": from, "$lte": to}}
+	opts := options.Find().SetSort(bson.D{{Key: "time", Value: 1}})
+
+	cursor, err := as.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Trading212 activity: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var activity []Trading212Activity
+	if err := cursor.All(ctx, &activity); err != nil {
+		return nil, fmt.Errorf("failed to decode Trading212 activity: %w", err)
+	}
+
+	return activity, nil
+}
+
+// activityKey identifies a row; the ID column alone is not unique, since a
+// single order can produce several rows and some actions have no ID at all
+func activityKey(account string, row stocks.ExportRow) string {
+	return strings.Join([]string{
+		account,
+		row.Action,
+		row.Time.UTC().Format(time.RFC3339Nano),
+		row.Ticker,
+		row.ID,
+		strconv.FormatFloat(row.Total, 'f', -1, 64),
+	}, "|")
+}
@@ -0,0 +1,408 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"investment-tracker/internal/portfolio"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Holding change actions
+const (
+	HoldingAdd    = "add"
+	HoldingSet    = "set"
+	HoldingRemove = "remove"
+)
+
+// HoldingsService keeps the holdings in the database with an append-only
+// history of every change; it implements valuation.HoldingsStore
+type HoldingsService struct {
+	db         *MongoDB
+	collection *mongo.Collection
+	history    *mongo.Collection
+}
+
+func NewHoldingsService(db *MongoDB) *HoldingsService {
+	return &HoldingsService{
+		db:         db,
+		collection: db.GetCollection("holdings"),
+		history:    db.GetCollection("holdings_history"),
+	}
+}
+
+// holdingKey identifies a holding by section and name
+type holdingKey struct {
+	section string
+	name    string
+}
+
+// CurrentHoldings returns the holdings as they stand now
+func (hs *HoldingsService) CurrentHoldings(ctx context.Context) (*portfolio.Holdings, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	entries, err := hs.currentEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// An empty portfolio is only real once something has been recorded
+	if len(entries) == 0 {
+		count, err := hs.history.CountDocuments(ctx, bson.M{}, options.Count().SetLimit(1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to query holdings history: %w", err)
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("no holdings stored yet, import them with: go run ./cmd/holdings import")
+		}
+	}
+
+	return portfolio.HoldingsFromEntries(entries)
+}
+
+// HoldingsAt replays the history to return the holdings as they stood at a time
+func (hs *HoldingsService) HoldingsAt(ctx context.Context, asOf time.Time) (*portfolio.Holdings, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	changes, err := hs.changes(ctx, bson.M{"time": bson.M{"$lte": asOf}})
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("no holdings were stored by %s", asOf.Format("2006-01-02 15:04"))
+	}
+
+	state := make(map[holdingKey]json.RawMessage)
+	var order []holdingKey
+	for _, change := range changes {
+		key := holdingKey{change.Section, change.Name}
+		if change.Action == HoldingRemove {
+			delete(state, key)
+			continue
+		}
+
+		value, err := ValueJSON(change.After)
+		if err != nil {
+			return nil, err
+		}
+		if _, seen := state[key]; !seen {
+			order = append(order, key)
+		}
+		state[key] = value
+	}
+
+	var entries []portfolio.Entry
+	for _, key := range order {
+		if value, ok := state[key]; ok {
+			entries = append(entries, portfolio.Entry{Section: key.section, Name: key.name, Value: value})
+		}
+	}
+
+	h, err := portfolio.HoldingsFromEntries(entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild holdings at %s: %w", asOf.Format("2006-01-02"), err)
+	}
+	return h, nil
+}
+
+// Add records a new holding; it fails when the holding already exists
+func (hs *HoldingsService) Add(ctx context.Context, section, name string, value json.RawMessage, reason string) error {
+	return hs.change(ctx, HoldingAdd, section, name, value, reason)
+}
+
+// Set records a holding, replacing it if it exists
+func (hs *HoldingsService) Set(ctx context.Context, section, name string, value json.RawMessage, reason string) error {
+	return hs.change(ctx, HoldingSet, section, name, value, reason)
+}
+
+// Remove records that a holding is no longer held
+func (hs *HoldingsService) Remove(ctx context.Context, section, name, reason string) error {
+	return hs.change(ctx, HoldingRemove, section, name, nil, reason)
+}
+
+// Import replaces all holdings with those of a holdings file, recording a
+// change for every holding that differs, and returns how many did
+func (hs *HoldingsService) Import(ctx context.Context, h *portfolio.Holdings, reason string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if reason == "" {
+		return 0, fmt.Errorf("a reason is required for every holdings change")
+	}
+
+	current, err := hs.currentEntries(ctx)
+	if err != nil {
+		return 0, err
+	}
+	before, err := canonical(current)
+	if err != nil {
+		return 0, fmt.Errorf("stored holdings are invalid: %w", err)
+	}
+
+	target, err := h.Entries()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var changes []HoldingChange
+	seen := make(map[holdingKey]bool)
+	for _, entry := range target {
+		key := holdingKey{entry.Section, entry.Name}
+		seen[key] = true
+
+		old, held := before[key]
+		if held && string(old) == string(entry.Value) {
+			continue
+		}
+		change, err := newHoldingChange(now, HoldingSet, entry.Section, entry.Name, old, entry.Value, reason)
+		if err != nil {
+			return 0, err
+		}
+		changes = append(changes, change)
+	}
+	for _, entry := range current {
+		key := holdingKey{entry.Section, entry.Name}
+		if seen[key] {
+			continue
+		}
+		change, err := newHoldingChange(now, HoldingRemove, entry.Section, entry.Name, before[key], nil, reason)
+		if err != nil {
+			return 0, err
+		}
+		changes = append(changes, change)
+	}
+
+	if err := hs.apply(ctx, changes...); err != nil {
+		return 0, err
+	}
+	return len(changes), nil
+}
+
+// History returns the recorded changes since a time, oldest first, optionally
+// only those of a section or a single holding
+func (hs *HoldingsService) History(ctx context.Context, section, name string, since time.Time) ([]HoldingChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{"time": bson.M{"$gte": since}}
+	if section != "" {
+		filter["section"] = section
+	}
+	if name != "" {
+		filter["name"] = name
+	}
+
+	return hs.changes(ctx, filter)
+}
+
+// change validates a single change against the rest of the holdings and records it
+func (hs *HoldingsService) change(ctx context.Context, action, section, name string, value json.RawMessage, reason string) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if reason == "" {
+		return fmt.Errorf("a reason is required for every holdings change")
+	}
+
+	current, err := hs.currentEntries(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := holdingKey{section, name}
+	var old json.RawMessage
+	var entries []portfolio.Entry
+	for _, entry := range current {
+		if (holdingKey{entry.Section, entry.Name}) == key {
+			old = entry.Value
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	switch {
+	case action == HoldingAdd && old != nil:
+		return fmt.Errorf("%s %s is already held, use set to replace it", section, name)
+	case action == HoldingRemove && old == nil:
+		return fmt.Errorf("%s %s is not held", section, name)
+	}
+
+	if action != HoldingRemove {
+		entries = append(entries, portfolio.Entry{Section: section, Name: name, Value: value})
+	}
+
+	// The whole portfolio is validated, and values are stored as they re-encode
+	h, err := portfolio.HoldingsFromEntries(entries)
+	if err != nil {
+		return err
+	}
+	if action != HoldingRemove {
+		entry, _, err := h.Entry(section, name)
+		if err != nil {
+			return err
+		}
+		value = entry.Value
+	}
+
+	change, err := newHoldingChange(time.Now(), action, section, name, old, value, reason)
+	if err != nil {
+		return err
+	}
+	return hs.apply(ctx, change)
+}
+
+// apply appends changes to the history, then brings the current holdings in
+// line; the history is written first as it is what past valuations replay
+func (hs *HoldingsService) apply(ctx context.Context, changes ...HoldingChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, len(changes))
+	var models []mongo.WriteModel
+	for i, change := range changes {
+		documents[i] = change
+
+		filter := bson.M{"section": change.Section, "name": change.Name}
+		if change.Action == HoldingRemove {
+			models = append(models, mongo.NewDeleteOneModel().SetFilter(filter))
+			continue
+		}
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(filter).
+			SetReplacement(HoldingEntry{
+				Section:   change.Section,
+				Name:      change.Name,
+				Value:     change.After,
+				UpdatedAt: change.Time,
+				Reason:    change.Reason,
+			}).
+			SetUpsert(true))
+	}
+
+	if _, err := hs.history.InsertMany(ctx, documents); err != nil {
+		return fmt.Errorf("failed to record holdings history: %w", err)
+	}
+	if _, err := hs.collection.BulkWrite(ctx, models); err != nil {
+		return fmt.Errorf("failed to update holdings: %w", err)
+	}
+	return nil
+}
+
+func (hs *HoldingsService) currentEntries(ctx context.Context) ([]portfolio.Entry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "section", Value: 1}, {Key: "name", Value: 1}})
+	cursor, err := hs.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query holdings: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stored []HoldingEntry
+	if err := cursor.All(ctx, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode holdings: %w", err)
+	}
+
+	entries := make([]portfolio.Entry, len(stored))
+	for i, entry := range stored {
+		value, err := ValueJSON(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = portfolio.Entry{Section: entry.Section, Name: entry.Name, Value: value}
+	}
+	return entries, nil
+}
+
+func (hs *HoldingsService) changes(ctx context.Context, filter bson.M) ([]HoldingChange, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "time", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := hs.history.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query holdings history: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var changes []HoldingChange
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, fmt.Errorf("failed to decode holdings history: %w", err)
+	}
+	return changes, nil
+}
+
+// canonical re-encodes entries as the holdings types write them, so values
+// compare equal however they were stored
+func canonical(entries []portfolio.Entry) (map[holdingKey]json.RawMessage, error) {
+	h, err := portfolio.HoldingsFromEntries(entries)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := h.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[holdingKey]json.RawMessage, len(encoded))
+	for _, entry := range encoded {
+		values[holdingKey{entry.Section, entry.Name}] = entry.Value
+	}
+	return values, nil
+}
+
+func newHoldingChange(at time.Time, action, section, name string, before, after json.RawMessage, reason string) (HoldingChange, error) {
+	change := HoldingChange{
+		Time:    at,
+		Action:  action,
+		Section: section,
+		Name:    name,
+		Reason:  reason,
+	}
+
+	var err error
+	if change.Before, err = valueBSON(before); err != nil {
+		return HoldingChange{}, err
+	}
+	if change.After, err = valueBSON(after); err != nil {
+		return HoldingChange{}, err
+	}
+	return change, nil
+}
+
+// valueBSON stores a holding's JSON as a native document, so it reads
+// naturally in the database; nil stays empty
+func valueBSON(value json.RawMessage) (bson.RawValue, error) {
+	if value == nil {
+		return bson.RawValue{}, nil
+	}
+
+	var document bson.Raw
+	if err := bson.UnmarshalExtJSON([]byte(`{"v":`+string(value)+`}`), false, &document); err != nil {
+		return bson.RawValue{}, fmt.Errorf("failed to encode holding %s: %w", value, err)
+	}
+	return document.Lookup("v"), nil
+}
+
+// ValueJSON returns a stored holding as it is written in the holdings file
+func ValueJSON(value bson.RawValue) (json.RawMessage, error) {
+	if value.IsZero() {
+		return nil, nil
+	}
+
+	data, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: value}}, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode holding: %w", err)
+	}
+
+	var wrapped struct {
+		V json.RawMessage `json:"v"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, fmt.Errorf("failed to decode holding: %w", err)
+	}
+	return wrapped.V, nil
+}
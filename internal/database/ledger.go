@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"investment-tracker/internal/portfolio"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LedgerService stores the crypto and bullion transactions that holdings,
+// cost basis and realised P&L are derived from
+type LedgerService struct {
+	db         *MongoDB
+	collection *mongo.Collection
+}
+
+func NewLedgerService(db *MongoDB) *LedgerService {
+	return &LedgerService{
+		db:         db,
+		collection: db.GetCollection("ledger"),
+	}
+}
+
+// Record adds a transaction, refusing one that would leave the ledger unable
+// to replay, such as a sale of more than was held at the time. It returns the
+// transaction's ID
+func (ls *LedgerService) Record(ctx context.Context, t portfolio.Transaction) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if err := t.Validate(); err != nil {
+		return "", err
+	}
+
+	transactions, err := ls.transactions(ctx, bson.M{"section": t.Section, "asset": t.Asset})
+	if err != nil {
+		return "", err
+	}
+	if _, err := portfolio.Replay(append(transactions, t)); err != nil {
+		return "", err
+	}
+
+	entry := LedgerTransaction{
+		Time:       t.Time,
+		Type:       t.Type,
+		Section:    t.Section,
+		Asset:      t.Asset,
+		Quantity:   t.Quantity,
+		Price:      t.Price,
+		Currency:   t.Currency,
+		Fee:        t.Fee,
+		From:       t.From,
+		To:         t.To,
+		Note:       t.Note,
+		RecordedAt: time.Now(),
+	}
+
+	result, err := ls.collection.InsertOne(ctx, entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to record transaction: %w", err)
+	}
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// Remove deletes a transaction recorded by mistake, unless the rest of the
+// ledger depends on it
+func (ls *LedgerService) Remove(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid transaction ID %q", id)
+	}
+
+	var entry LedgerTransaction
+	if err := ls.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&entry); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fmt.Errorf("no transaction %s", id)
+		}
+		return fmt.Errorf("failed to find transaction: %w", err)
+	}
+
+	transactions, err := ls.transactions(ctx, bson.M{
+		"section": entry.Section,
+		"asset":   entry.Asset,
+		"_id":     bson.M{"$ne": objectID},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := portfolio.Replay(transactions); err != nil {
+		return fmt.Errorf("later transactions depend on %s: %w", id, err)
+	}
+
+	if _, err := ls.collection.DeleteOne(ctx, bson.M{"_id": objectID}); err != nil {
+		return fmt.Errorf("failed to remove transaction: %w", err)
+	}
+	return nil
+}
+
+// Transactions returns the transactions up to a time, oldest first,
+// optionally only those of a section or a single asset
+func (ls *LedgerService) Transactions(ctx context.Context, section, asset string, until time.Time) ([]portfolio.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{"time": bson.M{"$lte": until}}
+	if section != "" {
+		filter["section"] = section
+	}
+	if asset != "" {
+		filter["asset"] = asset
+	}
+
+	return ls.transactions(ctx, filter)
+}
+
+func (ls *LedgerService) transactions(ctx context.Context, filter bson.M) ([]portfolio.Transaction, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "time", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := ls.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ledger: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var entries []LedgerTransaction
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode ledger: %w", err)
+	}
+
+	transactions := make([]portfolio.Transaction, len(entries))
+	for i, entry := range entries {
+		transactions[i] = portfolio.Transaction{
+			ID:       entry.ID.Hex(),
+			Time:     entry.Time,
+			Type:     entry.Type,
+			Section:  entry.Section,
+			Asset:    entry.Asset,
+			Quantity: entry.Quantity,
+			Price:    entry.Price,
+			Currency: entry.Currency,
+			Fee:      entry.Fee,
+			From:     entry.From,
+			To:       entry.To,
+			Note:     entry.Note,
+		}
+	}
+	return transactions, nil
+}
@@ -3,34 +3,157 @@ package database
 import (
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // PortfolioSnapshot represents a daily snapshot of portfolio values
 type PortfolioSnapshot struct {
-	ID               primitive.ObjectID `bson:"_id,omitempty"`
-	Date             time.Time          `bson:"date"`
-	Trading212BGN    float64            `bson:"trading212_bgn"`
-	CryptoBGN        float64            `bson:"crypto_bgn"`
-	BullionBGN       float64            `bson:"bullion_bgn"`
-	TotalBGN         float64            `bson:"total_bgn"`
-	CreatedAt        time.Time          `bson:"created_at"`
-	
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	Date       time.Time          `bson:"date"`
+	Currency   string             `bson:"currency"`
+	Trading212 float64            `bson:"trading212"`
+	Crypto     float64            `bson:"crypto"`
+	Bullion    float64            `bson:"bullion"`
+	Total      float64            `bson:"total"`
+	CreatedAt  time.Time          `bson:"created_at"`
+
+	// Legacy BGN-only amounts from before snapshots carried a currency,
+	// rewritten by MigrateCurrency
+	Trading212BGN float64 `bson:"trading212_bgn,omitempty"`
+	CryptoBGN     float64 `bson:"crypto_bgn,omitempty"`
+	BullionBGN    float64 `bson:"bullion_bgn,omitempty"`
+	TotalBGN      float64 `bson:"total_bgn,omitempty"`
+
 	// Optional: Store individual asset breakdowns
-	CryptoAssets     []AssetValue       `bson:"crypto_assets,omitempty"`
-	BullionAssets    []AssetValue       `bson:"bullion_assets,omitempty"`
-	
+	CryptoAssets  []AssetValue `bson:"crypto_assets,omitempty"`
+	BullionAssets []AssetValue `bson:"bullion_assets,omitempty"`
+	Pies          []PieValue   `bson:"pies,omitempty"`
+
+	// Trading212 value per account, keyed by account name in Symbol; Trading212
+	// holds their sum. Snapshots from before multiple accounts lack it
+	Trading212Accounts []AssetValue `bson:"trading212_accounts,omitempty"`
+
+	// Trading212 positions and cash split of each account valued live that day
+	Positions      []AssetValue `bson:"positions,omitempty"`
+	Trading212Cash []CashValue  `bson:"trading212_cash,omitempty"`
+
+	// Stocks and savings accounts tracked by hand in the holdings file
+	Equities        float64      `bson:"equities,omitempty"`
+	Savings         float64      `bson:"savings,omitempty"`
+	EquityAssets    []AssetValue `bson:"equity_assets,omitempty"`
+	SavingsAccounts []AssetValue `bson:"savings_accounts,omitempty"`
+
 	// Optional: Store exchange rates used
-	USDToBGNRate     float64            `bson:"usd_to_bgn_rate,omitempty"`
+	USDToBGNRate float64            `bson:"usd_to_bgn_rate,omitempty"`
+	FXRates      map[string]float64 `bson:"fx_rates,omitempty"`
+
+	// Set when one or more price sources failed or fell back to last known
+	// prices during the valuation
+	Incomplete     bool     `bson:"incomplete"`
+	MissingSources []string `bson:"missing_sources,omitempty"`
+	StaleSources   []string `bson:"stale_sources,omitempty"`
+
+	// Set when the snapshot was rebuilt after the fact from historical prices
+	Reconstructed bool `bson:"reconstructed,omitempty"`
+
+	// Set when the amounts were converted from another currency by a migration
+	ConvertedFrom string `bson:"converted_from,omitempty"`
+}
+
+// normalize fills the currency fields of legacy snapshots so readers
+// never have to look at the BGN-only fields
+func (s *PortfolioSnapshot) normalize() {
+	if s.Currency != "" {
+		return
+	}
+
+	s.Currency = "BGN"
+	s.Trading212 = s.Trading212BGN
+	s.Crypto = s.CryptoBGN
+	s.Bullion = s.BullionBGN
+	s.Total = s.TotalBGN
 }
 
 // AssetValue represents individual asset values
 type AssetValue struct {
-	Symbol    string  `bson:"symbol"`
-	Amount    float64 `bson:"amount"`
-	Price     float64 `bson:"price"`
-	Value     float64 `bson:"value"`
-	Currency  string  `bson:"currency"`
+	Symbol   string  `bson:"symbol"`
+	Amount   float64 `bson:"amount"`
+	Price    float64 `bson:"price"`
+	Value    float64 `bson:"value"`
+	Currency string  `bson:"currency"`
+
+	// When the price was observed; older than the snapshot for stale prices
+	AsOf  time.Time `bson:"as_of,omitempty"`
+	Stale bool      `bson:"stale,omitempty"`
+
+	// Trading212 positions only: the account when several are configured, the
+	// average price paid and the unrealised result, of which FxPnL is from currency moves
+	Account      string  `bson:"account,omitempty"`
+	AveragePrice float64 `bson:"average_price,omitempty"`
+	PnL          float64 `bson:"pnl,omitempty"`
+	FxPnL        float64 `bson:"fx_pnl,omitempty"`
+
+	// Holdings kept as lots: what they cost, with PnL as the unrealised result
+	CostBasis float64 `bson:"cost_basis,omitempty"`
+
+	// Trading212 positions only: the instrument name, type and trading currency
+	Name               string `bson:"name,omitempty"`
+	InstrumentType     string `bson:"instrument_type,omitempty"`
+	InstrumentCurrency string `bson:"instrument_currency,omitempty"`
+}
+
+// CashValue splits the value of a Trading212 account
+type CashValue struct {
+	Account  string  `bson:"account,omitempty"`
+	Free     float64 `bson:"free"`
+	Invested float64 `bson:"invested"`
+	Result   float64 `bson:"result"`
+	PieCash  float64 `bson:"pie_cash"`
+	Currency string  `bson:"currency"`
+}
+
+// PieValue represents the value of a Trading212 pie
+type PieValue struct {
+	Account  string  `bson:"account,omitempty"`
+	ID       int64   `bson:"id"`
+	Name     string  `bson:"name"`
+	Invested float64 `bson:"invested"`
+	Value    float64 `bson:"value"`
+	Result   float64 `bson:"result"`
+	Currency string  `bson:"currency"`
+}
+
+// Trading212Activity is one row of a Trading212 history export
+type Trading212Activity struct {
+	ID primitive.ObjectID `bson:"_id,omitempty"`
+
+	// Key identifies the row across overlapping exports
+	Key string `bson:"key"`
+
+	// Account names the Trading212 account the row was exported from
+	Account string `bson:"account"`
+
+	Action                 string    `bson:"action"`
+	Time                   time.Time `bson:"time"`
+	ISIN                   string    `bson:"isin,omitempty"`
+	Ticker                 string    `bson:"ticker,omitempty"`
+	Name                   string    `bson:"name,omitempty"`
+	Shares                 float64   `bson:"shares,omitempty"`
+	PricePerShare          float64   `bson:"price_per_share,omitempty"`
+	PriceCurrency          string    `bson:"price_currency,omitempty"`
+	ExchangeRate           float64   `bson:"exchange_rate,omitempty"`
+	Result                 float64   `bson:"result,omitempty"`
+	ResultCurrency         string    `bson:"result_currency,omitempty"`
+	Total                  float64   `bson:"total"`
+	TotalCurrency          string    `bson:"total_currency"`
+	WithholdingTax         float64   `bson:"withholding_tax,omitempty"`
+	WithholdingTaxCurrency string    `bson:"withholding_tax_currency,omitempty"`
+	ConversionFee          float64   `bson:"conversion_fee,omitempty"`
+	ConversionFeeCurrency  string    `bson:"conversion_fee_currency,omitempty"`
+	Notes                  string    `bson:"notes,omitempty"`
+	ExternalID             string    `bson:"external_id,omitempty"`
+	ImportedAt             time.Time `bson:"imported_at"`
 }
 
 // PortfolioStats represents aggregated statistics
@@ -43,4 +166,65 @@ type PortfolioStats struct {
 	WorstDayValue    float64   `bson:"worst_day_value"`
 	AverageValue     float64   `bson:"average_value"`
 	DaysTracked      int       `bson:"days_tracked"`
-}
\ No newline at end of file
+	Currency         string    `bson:"currency"`
+}
+
+// OrderAuditEntry records one Trading212 order preview, placement or cancellation
+type OrderAuditEntry struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	Time        time.Time          `bson:"time"`
+	Account     string             `bson:"account"`
+	Action      string             `bson:"action"`
+	Outcome     string             `bson:"outcome"`
+	PreviewID   string             `bson:"preview_id,omitempty"`
+	Ticker      string             `bson:"ticker,omitempty"`
+	OrderType   string             `bson:"order_type,omitempty"`
+	Quantity    float64            `bson:"quantity,omitempty"`
+	LimitPrice  float64            `bson:"limit_price,omitempty"`
+	StopPrice   float64            `bson:"stop_price,omitempty"`
+	TimeInForce string             `bson:"time_in_force,omitempty"`
+	Value       float64            `bson:"value,omitempty"`
+	Currency    string             `bson:"currency,omitempty"`
+	OrderID     int64              `bson:"order_id,omitempty"`
+	Reason      string             `bson:"reason,omitempty"`
+}
+
+// HoldingEntry is the current holding of one asset, such as crypto BTC. Value
+// is the holding as written in the holdings file
+type HoldingEntry struct {
+	Section   string        `bson:"section"`
+	Name      string        `bson:"name"`
+	Value     bson.RawValue `bson:"value"`
+	UpdatedAt time.Time     `bson:"updated_at"`
+	Reason    string        `bson:"reason"`
+}
+
+// HoldingChange records one change to the holdings; the history is only
+// ever appended to, so the holdings can be replayed to any past time
+type HoldingChange struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty"`
+	Time    time.Time          `bson:"time"`
+	Action  string             `bson:"action"`
+	Section string             `bson:"section"`
+	Name    string             `bson:"name"`
+	Before  bson.RawValue      `bson:"before,omitempty"`
+	After   bson.RawValue      `bson:"after,omitempty"`
+	Reason  string             `bson:"reason"`
+}
+
+// LedgerTransaction is one transaction of the crypto and bullion ledger
+type LedgerTransaction struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	Time       time.Time          `bson:"time"`
+	Type       string             `bson:"type"`
+	Section    string             `bson:"section"`
+	Asset      string             `bson:"asset"`
+	Quantity   float64            `bson:"quantity"`
+	Price      float64            `bson:"price,omitempty"`
+	Currency   string             `bson:"currency,omitempty"`
+	Fee        float64            `bson:"fee,omitempty"`
+	From       string             `bson:"from,omitempty"`
+	To         string             `bson:"to,omitempty"`
+	Note       string             `bson:"note,omitempty"`
+	RecordedAt time.Time          `bson:"recorded_at"`
+}
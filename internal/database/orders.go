@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"investment-tracker/internal/stocks"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// orderLockTTL is how long an order lock holds before it is taken over, so a
+// run that died while placing an order does not block the account for good
+const orderLockTTL = 2 * time.Minute
+
+// OrderAuditService is the audit log of every Trading212 order attempt; it
+// implements stocks.OrderAuditLog
+type OrderAuditService struct {
+	db         *MongoDB
+	collection *mongo.Collection
+	locks      *mongo.Collection
+}
+
+func NewOrderAuditService(db *MongoDB) *OrderAuditService {
+	return &OrderAuditService{
+		db:         db,
+		collection: db.GetCollection("trading212_order_audit"),
+		locks:      db.GetCollection("trading212_order_locks"),
+	}
+}
+
+// RecordOrder appends an order attempt to the audit log
+func (oas *OrderAuditService) RecordOrder(ctx context.Context, attempt stocks.OrderAttempt) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	entry := OrderAuditEntry{
+		Time:        attempt.Time,
+		Account:     attempt.Account,
+		Action:      attempt.Action,
+		Outcome:     attempt.Outcome,
+		PreviewID:   attempt.PreviewID,
+		Ticker:      attempt.Ticker,
+		OrderType:   string(attempt.OrderType),
+		Quantity:    attempt.Quantity,
+		LimitPrice:  attempt.LimitPrice,
+		StopPrice:   attempt.StopPrice,
+		TimeInForce: string(attempt.TimeInForce),
+		Value:       attempt.Value,
+		Currency:    attempt.Currency,
+		OrderID:     attempt.OrderID,
+		Reason:      attempt.Reason,
+	}
+
+	if _, err := oas.collection.InsertOne(ctx, entry); err != nil {
+		return fmt.Errorf("failed to record order attempt: %w", err)
+	}
+	return nil
+}
+
+// SubmittedValue totals the value of the orders an account sent to Trading212
+// since a time. An order counts unless Trading212 refused it, since a timeout
+// or a server error may still have placed it
+func (oas *OrderAuditService) SubmittedValue(ctx context.Context, account string, since time.Time) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Every attempt is recorded as submitted before it is sent, and a refusal
+	// takes its value back off
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"account": account,
+			"action":  stocks.AuditPlace,
+			"outcome": bson.M{"$in": []string{stocks.OutcomeSubmitted, stocks.OutcomeRefused}},
+			"time":    bson.M{"$gte": since},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": nil,
+			"value": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$outcome", stocks.OutcomeRefused}},
+				bson.M{"$multiply": bson.A{"$value", -1}},
+				"$value",
+			}}},
+		}}},
+	}
+
+	cursor, err := oas.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, fmt.Errorf("failed to total submitted orders: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var totals []struct {
+		Value float64 `bson:"value"`
+	}
+	if err := cursor.All(ctx, &totals); err != nil {
+		return 0, fmt.Errorf("failed to decode submitted order total: %w", err)
+	}
+	if len(totals) == 0 {
+		return 0, nil
+	}
+	return totals[0].Value, nil
+}
+
+// LockOrders takes the order lock of an account, so that runs in other
+// processes cannot pass the daily cap together. The lock lapses after
+// orderLockTTL in case the holder dies before calling unlock
+func (oas *OrderAuditService) LockOrders(ctx context.Context, account string) (func(), error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// The upsert only matches a lapsed lock; a live one makes it insert a
+	// second document with the same _id, which fails
+	token := primitive.NewObjectID()
+	now := time.Now()
+	_, err := oas.locks.UpdateOne(ctx,
+		bson.M{"_id": account, "expires_at": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{"token": token, "expires_at": now.Add(orderLockTTL)}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, fmt.Errorf("another order is being placed on the %s account, try again shortly", account)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take the order lock: %w", err)
+	}
+
+	unlock := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if _, err := oas.locks.DeleteOne(ctx, bson.M{"_id": account, "token": token}); err != nil {
+			log.Printf("Warning: Could not release the %s order lock, it lapses in %s: %v", account, orderLockTTL, err)
+		}
+	}
+	return unlock, nil
+}
+
+// GetOrderAudit returns the order attempts since a time, newest first
+func (oas *OrderAuditService) GetOrderAudit(since time.Time) ([]OrderAuditEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "time", Value: -1}})
+	cursor, err := oas.collection.Find(ctx, bson.M{"time": bson.M{"$gte": since}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order audit log: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var entries []OrderAuditEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode order audit log: %w", err)
+	}
+	return entries, nil
+}
@@ -3,6 +3,9 @@ package database
 import (
 	"context"
 	"fmt"
+	"investment-tracker/internal/conversion"
+	"investment-tracker/internal/stocks"
+	"investment-tracker/internal/valuation"
 	"log"
 	"time"
 
@@ -11,6 +14,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// legacyFields are the BGN-only amount fields replaced by currency + amount
+var legacyFields = []string{"trading212_bgn", "crypto_bgn", "bullion_bgn", "total_bgn"}
+
 type PortfolioService struct {
 	db         *MongoDB
 	collection *mongo.Collection
@@ -23,27 +29,53 @@ func NewPortfolioService(db *MongoDB) *PortfolioService {
 	}
 }
 
-// SaveDailySnapshot saves a daily portfolio snapshot
-func (ps *PortfolioService) SaveDailySnapshot(trading212, crypto, bullion, total float64) error {
+// SaveDailySnapshot saves a daily portfolio snapshot from a valuation
+func (ps *PortfolioService) SaveDailySnapshot(v *valuation.Valuation) error {
+	// Use today's date (without time) as the key
+	return ps.SaveSnapshot(time.Now(), v)
+}
+
+// SaveSnapshot saves a valuation as the snapshot for the given day. A
+// reconstructed valuation only fills a missing day and never replaces a snapshot
+func (ps *PortfolioService) SaveSnapshot(date time.Time, v *valuation.Valuation) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Use today's date (without time) as the key
-	today := time.Now().Truncate(24 * time.Hour)
+	today := date.Truncate(24 * time.Hour)
+	snapshot := newSnapshot(today, v)
 
-	snapshot := PortfolioSnapshot{
-		Date:          today,
-		Trading212BGN: trading212,
-		CryptoBGN:     crypto,
-		BullionBGN:    bullion,
-		TotalBGN:      total,
-		CreatedAt:     time.Now(),
-		USDToBGNRate:  1.7346, // You might want to make this dynamic
+	if snapshot.Incomplete || snapshot.Reconstructed {
+		existing, err := ps.findSnapshot(ctx, today)
+		if err != nil {
+			return err
+		}
+		if snapshot.keeps(existing) && snapshot.Reconstructed {
+			log.Printf("Keeping existing portfolio snapshot for %s", today.Format("2006-01-02"))
+			return nil
+		}
+		if snapshot.keeps(existing) {
+			log.Printf("Keeping complete portfolio snapshot for %s, new valuation is missing %v and stale for %v",
+				today.Format("2006-01-02"), snapshot.MissingSources, snapshot.StaleSources)
+			return nil
+		}
 	}
 
 	// Use upsert to replace if already exists for today
 	filter := bson.M{"date": today}
-	update := bson.M{"$set": snapshot}
+	unset := bson.M{}
+	for _, field := range legacyFields {
+		unset[field] = ""
+	}
+	if len(snapshot.MissingSources) == 0 {
+		unset["missing_sources"] = ""
+	}
+	if len(snapshot.StaleSources) == 0 {
+		unset["stale_sources"] = ""
+	}
+	if !snapshot.Reconstructed {
+		unset["reconstructed"] = ""
+	}
+	update := bson.M{"$set": snapshot, "$unset": unset}
 	opts := options.Update().SetUpsert(true)
 
 	result, err := ps.collection.UpdateOne(ctx, filter, update, opts)
@@ -60,13 +92,259 @@ func (ps *PortfolioService) SaveDailySnapshot(trading212, crypto, bullion, total
 	return nil
 }
 
+// newSnapshot builds the snapshot of a valuation for a day. A valuation
+// with failed sources, or sources that fell back to last known prices, is incomplete
+func newSnapshot(today time.Time, v *valuation.Valuation) PortfolioSnapshot {
+	snapshot := PortfolioSnapshot{
+		Date:          today,
+		Currency:      v.Currency,
+		Trading212:    v.Subtotal(valuation.ClassStocks),
+		Crypto:        v.Subtotal(valuation.ClassCrypto),
+		Bullion:       v.Subtotal(valuation.ClassBullion),
+		Total:         v.Total,
+		CreatedAt:     time.Now(),
+		CryptoAssets:  assetValues(v.LinesFor(valuation.ClassCrypto)),
+		BullionAssets: assetValues(v.LinesFor(valuation.ClassBullion)),
+		Pies:          pieValues(v),
+		USDToBGNRate:  v.Rates["USD/BGN"],
+		FXRates:       v.Rates,
+		Incomplete:    !v.Complete() || len(v.StaleSources()) > 0,
+		Reconstructed: v.Reconstructed,
+
+		Trading212Accounts: assetValues(v.LinesFor(valuation.ClassStocks)),
+		Positions:          positionValues(v),
+		Trading212Cash:     cashValues(v),
+
+		Equities:        v.Subtotal(valuation.ClassEquities),
+		Savings:         v.Subtotal(valuation.ClassSavings),
+		EquityAssets:    assetValues(v.LinesFor(valuation.ClassEquities)),
+		SavingsAccounts: assetValues(v.LinesFor(valuation.ClassSavings)),
+	}
+
+	for _, source := range v.Failed() {
+		snapshot.MissingSources = append(snapshot.MissingSources, source.Source)
+	}
+	for _, source := range v.StaleSources() {
+		snapshot.StaleSources = append(snapshot.StaleSources, source.Source)
+	}
+
+	return snapshot
+}
+
+// keeps reports whether an existing snapshot for the same day must be kept
+// instead of being replaced by this one: a reconstructed snapshot never
+// replaces anything, and an incomplete one never replaces a complete one
+func (s *PortfolioSnapshot) keeps(existing *PortfolioSnapshot) bool {
+	if existing == nil {
+		return false
+	}
+	if s.Reconstructed {
+		return true
+	}
+	return s.Incomplete && !existing.Incomplete
+}
+
+func assetValues(lines []valuation.Line) []AssetValue {
+	var assets []AssetValue
+	for _, line := range lines {
+		asset := AssetValue{
+			Symbol:   line.Symbol,
+			Amount:   line.Amount,
+			Price:    line.Price,
+			Value:    line.Value,
+			Currency: line.Currency,
+			AsOf:     line.Timestamp,
+			Stale:    line.Stale,
+		}
+		if performance, ok := line.Performance(); ok {
+			asset.CostBasis = performance.Cost
+			asset.PnL = performance.PnL()
+		}
+		assets = append(assets, asset)
+	}
+	return assets
+}
+
+func pieValues(v *valuation.Valuation) []PieValue {
+	var pies []PieValue
+	for _, pie := range v.Pies {
+		pies = append(pies, PieValue{
+			Account:  pie.Account,
+			ID:       pie.ID,
+			Name:     pie.Name,
+			Invested: pie.Invested,
+			Value:    pie.Value,
+			Result:   pie.Result,
+			Currency: v.Currency,
+		})
+	}
+	return pies
+}
+
+func positionValues(v *valuation.Valuation) []AssetValue {
+	var positions []AssetValue
+	for _, position := range v.Positions {
+		positions = append(positions, AssetValue{
+			Symbol:       position.Ticker,
+			Amount:       position.Quantity,
+			Price:        position.Price,
+			Value:        position.Value,
+			Currency:     v.Currency,
+			AsOf:         v.Timestamp,
+			Account:      position.Account,
+			AveragePrice: position.AveragePrice,
+			PnL:          position.PnL,
+			FxPnL:        position.FxPnL,
+
+			Name:               position.Name,
+			InstrumentType:     position.Type,
+			InstrumentCurrency: position.Currency,
+		})
+	}
+	return positions
+}
+
+func cashValues(v *valuation.Valuation) []CashValue {
+	var cash []CashValue
+	for _, account := range v.Cash {
+		cash = append(cash, CashValue{
+			Account:  account.Account,
+			Free:     account.Free,
+			Invested: account.Invested,
+			Result:   account.Result,
+			PieCash:  account.PieCash,
+			Currency: v.Currency,
+		})
+	}
+	return cash
+}
+
+// findSnapshot returns the snapshot for a date, or nil if there is none
+func (ps *PortfolioService) findSnapshot(ctx context.Context, date time.Time) (*PortfolioSnapshot, error) {
+	var snapshot PortfolioSnapshot
+	err := ps.collection.FindOne(ctx, bson.M{"date": date}).Decode(&snapshot)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get snapshot for %s: %w", date.Format("2006-01-02"), err)
+	}
+
+	snapshot.normalize()
+	return &snapshot, nil
+}
+
+// LastPrice returns the most recently recorded price of an asset on or before
+// a point in time, or nil if the asset appears in no such snapshot. It lets
+// valuations fall back to last known prices
+func (ps *PortfolioService) LastPrice(ctx context.Context, class valuation.AssetClass, symbol string, before time.Time) (*valuation.KnownPrice, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"date": bson.M{"$lte": before}}
+	switch class {
+	case valuation.ClassCrypto:
+		filter["crypto_assets.symbol"] = symbol
+	case valuation.ClassBullion:
+		filter["bullion_assets.symbol"] = symbol
+	case valuation.ClassEquities:
+		filter["equity_assets.symbol"] = symbol
+	case valuation.ClassStocks:
+		if symbol != stocks.DefaultAccount {
+			filter["trading212_accounts.symbol"] = symbol
+			break
+		}
+		// Older snapshots store the single account as one amount, valid unless
+		// Trading212 failed that day; reconstructed ones only carry an older value forward
+		filter["$or"] = bson.A{bson.M{"trading212_accounts.symbol": symbol}, bson.M{
+			"trading212_accounts": bson.M{"$exists": false},
+			"missing_sources":     bson.M{"$ne": "trading212"},
+			"reconstructed":       bson.M{"$ne": true},
+		}}
+	default:
+		return nil, fmt.Errorf("no price history for %s assets", class)
+	}
+
+	var snapshot PortfolioSnapshot
+	opts := options.FindOne().SetSort(bson.D{{Key: "date", Value: -1}})
+	err := ps.collection.FindOne(ctx, filter, opts).Decode(&snapshot)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get last price for %s: %w", symbol, err)
+	}
+	snapshot.normalize()
+
+	if class == valuation.ClassStocks && len(snapshot.Trading212Accounts) == 0 {
+		return &valuation.KnownPrice{
+			Price:    snapshot.Trading212,
+			Currency: snapshot.Currency,
+			AsOf:     snapshot.CreatedAt,
+		}, nil
+	}
+
+	assets := snapshot.CryptoAssets
+	switch class {
+	case valuation.ClassBullion:
+		assets = snapshot.BullionAssets
+	case valuation.ClassEquities:
+		assets = snapshot.EquityAssets
+	case valuation.ClassStocks:
+		assets = snapshot.Trading212Accounts
+	}
+
+	for _, asset := range assets {
+		if asset.Symbol != symbol {
+			continue
+		}
+
+		known := &valuation.KnownPrice{
+			Price:    asset.Price,
+			Currency: asset.Currency,
+			AsOf:     asset.AsOf,
+		}
+		if known.Currency == "" {
+			known.Currency = snapshot.Currency
+		}
+		if known.AsOf.IsZero() {
+			known.AsOf = snapshot.CreatedAt
+		}
+		return known, nil
+	}
+
+	return nil, nil
+}
+
+// MissingDays returns the days between start and end, inclusive, without a snapshot
+func (ps *PortfolioService) MissingDays(start, end time.Time) ([]time.Time, error) {
+	snapshots, err := ps.GetSnapshotsByDateRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool)
+	for _, snapshot := range snapshots {
+		existing[snapshot.Date.Format("2006-01-02")] = true
+	}
+
+	var missing []time.Time
+	for day := start.Truncate(24 * time.Hour); !day.After(end); day = day.AddDate(0, 0, 1) {
+		if !existing[day.Format("2006-01-02")] {
+			missing = append(missing, day)
+		}
+	}
+
+	return missing, nil
+}
+
 // GetLatestSnapshot returns the most recent portfolio snapshot
 func (ps *PortfolioService) GetLatestSnapshot() (*PortfolioSnapshot, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	var snapshot PortfolioSnapshot
-	opts := options.FindOne().SetSort(bson.D{{"date", -1}})
+	opts := options.FindOne().SetSort(bson.D{{Key: "date", Value: -1}})
 
 	err := ps.collection.FindOne(ctx, bson.M{}, opts).Decode(&snapshot)
 	if err != nil {
@@ -76,6 +354,7 @@ func (ps *PortfolioService) GetLatestSnapshot() (*PortfolioSnapshot, error) {
 		return nil, fmt.Errorf("failed to get latest snapshot: %w", err)
 	}
 
+	snapshot.normalize()
 	return &snapshot, nil
 }
 
@@ -91,7 +370,7 @@ func (ps *PortfolioService) GetSnapshotsByDateRange(start, end time.Time) ([]Por
 		},
 	}
 
-	opts := options.Find().SetSort(bson.D{{"date", 1}})
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}})
 	cursor, err := ps.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query snapshots: %w", err)
@@ -104,6 +383,10 @@ func (ps *PortfolioService) GetSnapshotsByDateRange(start, end time.Time) ([]Por
 		return nil, fmt.Errorf("failed to decode snapshots: %w", err)
 	}
 
+	for i := range snapshots {
+		snapshots[i].normalize()
+	}
+
 	return snapshots, nil
 }
 
@@ -111,17 +394,38 @@ func (ps *PortfolioService) GetSnapshotsByDateRange(start, end time.Time) ([]Por
 func (ps *PortfolioService) GetLastNDays(days int) ([]PortfolioSnapshot, error) {
 	end := time.Now()
 	start := end.AddDate(0, 0, -days)
-	
+
 	return ps.GetSnapshotsByDateRange(start, end)
 }
 
+// GetPositionHistory returns a Trading212 position as recorded in each of the
+// last N days of snapshots, one entry per account holding it, oldest first
+func (ps *PortfolioService) GetPositionHistory(ticker string, days int) ([]AssetValue, error) {
+	snapshots, err := ps.GetLastNDays(days)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []AssetValue
+	for _, snapshot := range snapshots {
+		for _, position := range snapshot.Positions {
+			if position.Symbol == ticker {
+				history = append(history, position)
+			}
+		}
+	}
+	return history, nil
+}
+
 // GetPortfolioStats calculates portfolio statistics
 func (ps *PortfolioService) GetPortfolioStats() (*PortfolioStats, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Get all snapshots
-	cursor, err := ps.collection.Find(ctx, bson.M{})
+	// Get all complete snapshots; incomplete ones would skew best/worst days
+	filter := bson.M{"incomplete": bson.M{"$ne": true}}
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}})
+	cursor, err := ps.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get snapshots for stats: %w", err)
 	}
@@ -137,27 +441,36 @@ func (ps *PortfolioService) GetPortfolioStats() (*PortfolioStats, error) {
 		return nil, fmt.Errorf("no data available for statistics")
 	}
 
+	for i := range snapshots {
+		snapshots[i].normalize()
+		if snapshots[i].Currency != snapshots[0].Currency {
+			return nil, fmt.Errorf("snapshots mix %s and %s amounts, run the currency migration first",
+				snapshots[0].Currency, snapshots[i].Currency)
+		}
+	}
+
 	// Calculate stats
 	stats := &PortfolioStats{
 		DaysTracked: len(snapshots),
+		Currency:    snapshots[0].Currency,
 	}
 
 	var totalValue float64
-	stats.BestDayValue = snapshots[0].TotalBGN
-	stats.WorstDayValue = snapshots[0].TotalBGN
+	stats.BestDayValue = snapshots[0].Total
+	stats.WorstDayValue = snapshots[0].Total
 	stats.BestDay = snapshots[0].Date
 	stats.WorstDay = snapshots[0].Date
 
 	for _, snapshot := range snapshots {
-		totalValue += snapshot.TotalBGN
+		totalValue += snapshot.Total
 
-		if snapshot.TotalBGN > stats.BestDayValue {
-			stats.BestDayValue = snapshot.TotalBGN
+		if snapshot.Total > stats.BestDayValue {
+			stats.BestDayValue = snapshot.Total
 			stats.BestDay = snapshot.Date
 		}
 
-		if snapshot.TotalBGN < stats.WorstDayValue {
-			stats.WorstDayValue = snapshot.TotalBGN
+		if snapshot.Total < stats.WorstDayValue {
+			stats.WorstDayValue = snapshot.Total
 			stats.WorstDay = snapshot.Date
 		}
 	}
@@ -166,8 +479,8 @@ func (ps *PortfolioService) GetPortfolioStats() (*PortfolioStats, error) {
 
 	// Calculate growth (first vs last)
 	if len(snapshots) > 1 {
-		firstValue := snapshots[0].TotalBGN
-		lastValue := snapshots[len(snapshots)-1].TotalBGN
+		firstValue := snapshots[0].Total
+		lastValue := snapshots[len(snapshots)-1].Total
 		stats.TotalGrowth = lastValue - firstValue
 		stats.GrowthPercentage = (stats.TotalGrowth / firstValue) * 100
 	}
@@ -192,6 +505,7 @@ func (ps *PortfolioService) GetTodaySnapshot() (*PortfolioSnapshot, error) {
 		return nil, fmt.Errorf("failed to get today's snapshot: %w", err)
 	}
 
+	snapshot.normalize()
 	return &snapshot, nil
 }
 
@@ -213,4 +527,128 @@ func (ps *PortfolioService) DeleteOldSnapshots(daysToKeep int) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// MigrateCurrency converts every snapshot not yet stored in the target currency,
+// including legacy BGN-only snapshots, and returns the number of snapshots rewritten
+func (ps *PortfolioService) MigrateCurrency(to string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cursor, err := ps.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get snapshots for migration: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var snapshots []PortfolioSnapshot
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return 0, fmt.Errorf("failed to decode snapshots: %w", err)
+	}
+
+	migrated := 0
+	for _, snapshot := range snapshots {
+		legacy := snapshot.Currency == ""
+		snapshot.normalize()
+		if snapshot.Currency == to && !legacy {
+			continue
+		}
+
+		from := snapshot.Currency
+		rate, err := conversion.Default().Rate(ctx, from, to, snapshot.Date)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to migrate snapshot for %s: %w", snapshot.Date.Format("2006-01-02"), err)
+		}
+
+		set := bson.M{
+			"currency":       to,
+			"trading212":     snapshot.Trading212 * rate,
+			"crypto":         snapshot.Crypto * rate,
+			"bullion":        snapshot.Bullion * rate,
+			"total":          snapshot.Total * rate,
+			"crypto_assets":  convertAssets(snapshot.CryptoAssets, from, to, rate),
+			"bullion_assets": convertAssets(snapshot.BullionAssets, from, to, rate),
+		}
+		if len(snapshot.Pies) > 0 {
+			set["pies"] = convertPies(snapshot.Pies, from, to, rate)
+		}
+		if len(snapshot.EquityAssets) > 0 || len(snapshot.SavingsAccounts) > 0 {
+			set["equities"] = snapshot.Equities * rate
+			set["savings"] = snapshot.Savings * rate
+			set["equity_assets"] = convertAssets(snapshot.EquityAssets, from, to, rate)
+			set["savings_accounts"] = convertAssets(snapshot.SavingsAccounts, from, to, rate)
+		}
+		if len(snapshot.Trading212Accounts) > 0 {
+			set["trading212_accounts"] = convertAssets(snapshot.Trading212Accounts, from, to, rate)
+		}
+		if len(snapshot.Positions) > 0 {
+			set["positions"] = convertAssets(snapshot.Positions, from, to, rate)
+		}
+		if len(snapshot.Trading212Cash) > 0 {
+			set["trading212_cash"] = convertCash(snapshot.Trading212Cash, from, to, rate)
+		}
+		if from != to {
+			set["converted_from"] = from
+		}
+
+		unset := bson.M{}
+		for _, field := range legacyFields {
+			unset[field] = ""
+		}
+
+		_, err = ps.collection.UpdateOne(ctx, bson.M{"_id": snapshot.ID}, bson.M{"$set": set, "$unset": unset})
+		if err != nil {
+			return migrated, fmt.Errorf("failed to update snapshot for %s: %w", snapshot.Date.Format("2006-01-02"), err)
+		}
+		migrated++
+	}
+
+	log.Printf("Migrated %d portfolio snapshots to %s", migrated, to)
+	return migrated, nil
+}
+
+func convertAssets(assets []AssetValue, from, to string, rate float64) []AssetValue {
+	converted := make([]AssetValue, 0, len(assets))
+	for _, asset := range assets {
+		if asset.Currency == from {
+			asset.Price *= rate
+			asset.Value *= rate
+			asset.AveragePrice *= rate
+			asset.PnL *= rate
+			asset.FxPnL *= rate
+			asset.CostBasis *= rate
+			asset.Currency = to
+		}
+		converted = append(converted, asset)
+	}
+	return converted
+}
+
+func convertPies(pies []PieValue, from, to string, rate float64) []PieValue {
+	converted := make([]PieValue, 0, len(pies))
+	for _, pie := range pies {
+		if pie.Currency == from {
+			pie.Invested *= rate
+			pie.Value *= rate
+			pie.Result *= rate
+			pie.Currency = to
+		}
+		converted = append(converted, pie)
+	}
+	return converted
+}
+
+func convertCash(cash []CashValue, from, to string, rate float64) []CashValue {
+	converted := make([]CashValue, 0, len(cash))
+	for _, account := range cash {
+		if account.Currency == from {
+			account.Free *= rate
+			account.Invested *= rate
+			account.Result *= rate
+			account.PieCash *= rate
+			account.Currency = to
+		}
+		converted = append(converted, account)
+	}
+	return converted
+}
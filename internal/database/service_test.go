@@ -0,0 +1,92 @@
+package database
+
+import (
+	"errors"
+	"investment-tracker/internal/valuation"
+	"testing"
+	"time"
+)
+
+// testValuation values one crypto holding, live or at a last known price
+func testValuation(stale bool) *valuation.Valuation {
+	v := valuation.New("EUR")
+	v.Add(valuation.Line{
+		Class:    valuation.ClassCrypto,
+		Symbol:   "BTC",
+		Amount:   1,
+		Price:    50000,
+		Value:    50000,
+		Currency: "EUR",
+		Source:   "coingecko",
+		Stale:    stale,
+	})
+
+	status := valuation.StatusOK
+	if stale {
+		status = valuation.StatusStale
+	}
+	v.Sources = append(v.Sources, valuation.SourceResult{
+		Source: "coingecko",
+		Class:  valuation.ClassCrypto,
+		Status: status,
+		AsOf:   time.Now(),
+	})
+	return v
+}
+
+func TestStaleValuationIsIncomplete(t *testing.T) {
+	today := time.Now().Truncate(24 * time.Hour)
+
+	snapshot := newSnapshot(today, testValuation(true))
+	if !snapshot.Incomplete {
+		t.Error("a valuation from last known prices is complete, want incomplete")
+	}
+	if len(snapshot.StaleSources) != 1 || snapshot.StaleSources[0] != "coingecko" {
+		t.Errorf("stale sources = %v, want [coingecko]", snapshot.StaleSources)
+	}
+	if len(snapshot.MissingSources) != 0 {
+		t.Errorf("missing sources = %v, want none", snapshot.MissingSources)
+	}
+
+	live := newSnapshot(today, testValuation(false))
+	if live.Incomplete || len(live.StaleSources) != 0 {
+		t.Errorf("live valuation incomplete %v, stale %v, want complete", live.Incomplete, live.StaleSources)
+	}
+}
+
+func TestSnapshotKeepsCompleteSnapshotOfTheDay(t *testing.T) {
+	today := time.Now().Truncate(24 * time.Hour)
+
+	complete := newSnapshot(today, testValuation(false))
+
+	failed := testValuation(false)
+	failed.Sources = nil
+	failed.Record(valuation.ClassCrypto, "coingecko", errors.New("down"))
+
+	reconstructed := newSnapshot(today, testValuation(false))
+	reconstructed.Reconstructed = true
+
+	stale := newSnapshot(today, testValuation(true))
+
+	tests := []struct {
+		name     string
+		snapshot PortfolioSnapshot
+		existing *PortfolioSnapshot
+		keep     bool
+	}{
+		{"stale over complete", stale, &complete, true},
+		{"failed over complete", newSnapshot(today, failed), &complete, true},
+		{"stale over stale", stale, &stale, false},
+		{"stale on an empty day", stale, nil, false},
+		{"complete over stale", complete, &stale, false},
+		{"reconstructed over anything", reconstructed, &stale, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if keep := tt.snapshot.keeps(tt.existing); keep != tt.keep {
+				t.Errorf("keeps existing = %v, want %v", keep, tt.keep)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package filecache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File is a list downloaded from an API and persisted as JSON, so that it is
+// only downloaded again once it is older than its maximum age. It is not safe
+// for concurrent use; its owner guards it along with whatever it derives from the list
+type File[T any] struct {
+	name   string
+	key    string
+	path   string
+	maxAge time.Duration
+
+	fetchedAt time.Time
+	items     []T
+}
+
+// Open loads the file at path, keeping the list under key next to its
+// fetched_at time. A missing or unreadable file starts an empty list; name
+// describes the list in messages, e.g. "instrument catalogue"
+func Open[T any](name, key, path string, maxAge time.Duration) *File[T] {
+	f := &File[T]{
+		name:   name,
+		key:    key,
+		path:   path,
+		maxAge: maxAge,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: Could not read %s %s: %v", name, path, err)
+		}
+		return f
+	}
+
+	var file map[string]json.RawMessage
+	err = json.Unmarshal(data, &file)
+	if err == nil {
+		err = json.Unmarshal(file["fetched_at"], &f.fetchedAt)
+	}
+	if err == nil {
+		err = json.Unmarshal(file[key], &f.items)
+	}
+	if err != nil {
+		log.Printf("Warning: Ignoring corrupt %s %s: %v", name, path, err)
+		f.fetchedAt = time.Time{}
+		f.items = nil
+	}
+
+	return f
+}
+
+// Path returns the path in the env variable, or file in the investment-tracker
+// directory of the user cache directory
+func Path(env, file string) string {
+	if path := os.Getenv(env); path != "" {
+		return path
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "investment-tracker", file)
+}
+
+// SetMaxAge sets how long the list is used before it is downloaded again
+func (f *File[T]) SetMaxAge(maxAge time.Duration) {
+	f.maxAge = maxAge
+}
+
+// Items returns the list
+func (f *File[T]) Items() []T {
+	return f.items
+}
+
+// FetchedAt returns when the list was last downloaded
+func (f *File[T]) FetchedAt() time.Time {
+	return f.fetchedAt
+}
+
+// Stale reports whether the list is empty or older than its maximum age
+func (f *File[T]) Stale() bool {
+	return len(f.items) == 0 || time.Since(f.fetchedAt) > f.maxAge
+}
+
+// Refresh downloads the list with fetch and writes the file; failing to write
+// it only costs a download on the next run
+func (f *File[T]) Refresh(ctx context.Context, fetch func(ctx context.Context) ([]T, error)) error {
+	items, err := fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	f.fetchedAt = time.Now()
+	f.items = items
+
+	if err := f.save(); err != nil {
+		log.Printf("Warning: Could not save %s: %v", f.name, err)
+	}
+	return nil
+}
+
+// EnsureFresh refreshes a stale list and reports whether it changed. A failed
+// refresh keeps serving the previous list, which is only an error when there is none
+func (f *File[T]) EnsureFresh(ctx context.Context, fetch func(ctx context.Context) ([]T, error)) (bool, error) {
+	if !f.Stale() {
+		return false, nil
+	}
+
+	err := f.Refresh(ctx, fetch)
+	if err != nil {
+		if len(f.items) > 0 {
+			log.Printf("Warning: Could not refresh %s, using the list from %s: %v",
+				f.name, f.fetchedAt.Format("2006-01-02 15:04"), err)
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// save writes the file to a temporary file and renames it into place
+func (f *File[T]) save() error {
+	data, err := json.Marshal(map[string]interface{}{
+		"fetched_at": f.fetchedAt,
+		f.key:        f.items,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", f.name, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", f.name, err)
+	}
+
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", f.name, err)
+	}
+
+	return os.Rename(tmp, f.path)
+}
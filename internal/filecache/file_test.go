@@ -0,0 +1,67 @@
+package filecache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileRoundTripsAndKeepsListOnFailedRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.json")
+	ctx := context.Background()
+
+	f := Open[string]("test list", "names", path, time.Hour)
+	if !f.Stale() {
+		t.Fatal("an empty list is not stale")
+	}
+
+	refreshed, err := f.EnsureFresh(ctx, func(ctx context.Context) ([]string, error) {
+		return []string{"a", "b"}, nil
+	})
+	if err != nil || !refreshed {
+		t.Fatalf("EnsureFresh = %v, %v, want a refresh", refreshed, err)
+	}
+
+	reopened := Open[string]("test list", "names", path, time.Hour)
+	if got := reopened.Items(); len(got) != 2 || got[1] != "b" {
+		t.Fatalf("reopened items = %v, want [a b]", got)
+	}
+	if reopened.Stale() {
+		t.Error("a freshly saved list is stale after reopening")
+	}
+
+	// Once stale, a failed download keeps serving the saved list
+	reopened.SetMaxAge(0)
+	refreshed, err = reopened.EnsureFresh(ctx, func(ctx context.Context) ([]string, error) {
+		return nil, errors.New("HTTP 503")
+	})
+	if err != nil || refreshed || len(reopened.Items()) != 2 {
+		t.Errorf("EnsureFresh = %v, %v with %v, want the previous list kept", refreshed, err, reopened.Items())
+	}
+}
+
+func TestFailedRefreshWithoutListFails(t *testing.T) {
+	f := Open[string]("test list", "names", filepath.Join(t.TempDir(), "list.json"), time.Hour)
+
+	_, err := f.EnsureFresh(context.Background(), func(ctx context.Context) ([]string, error) {
+		return nil, errors.New("HTTP 503")
+	})
+	if err == nil {
+		t.Error("EnsureFresh succeeded without any list, want the download error")
+	}
+}
+
+func TestCorruptFileStartsEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.json")
+	if err := os.WriteFile(path, []byte(`{"fetched_at": "2024-01-01T00:00:00Z", "names": {}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := Open[string]("test list", "names", path, time.Hour)
+	if len(f.Items()) != 0 || !f.FetchedAt().IsZero() {
+		t.Errorf("corrupt file loaded %v from %s, want an empty list", f.Items(), f.FetchedAt())
+	}
+}
@@ -0,0 +1,98 @@
+package fx
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const DefaultECBBaseURL = "https://www.ecb.europa.eu/stats/eurofxref"
+
+// ECB reference rate feeds, relative to the base URL
+const (
+	FeedDaily      = "/eurofxref-daily.xml"
+	FeedLast90     = "/eurofxref-hist-90d.xml"
+	FeedHistorical = "/eurofxref-hist.xml"
+)
+
+// ECBProvider downloads euro foreign exchange reference rates from the ECB
+type ECBProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+type ecbEnvelope struct {
+	Cube struct {
+		Days []struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string  `xml:"currency,attr"`
+				Rate     float64 `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+func NewECBProvider(baseURL string) *ECBProvider {
+	if baseURL == "" {
+		baseURL = DefaultECBBaseURL
+	}
+
+	return &ECBProvider{
+		baseURL: baseURL,
+		client: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+	}
+}
+
+// Fetch downloads a feed and returns its rate tables, newest first
+func (p *ECBProvider) Fetch(ctx context.Context, feed string) ([]*RateTable, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+feed, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("ECB API error: HTTP %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse ECB response: %w", err)
+	}
+
+	var tables []*RateTable
+	for _, day := range envelope.Cube.Days {
+		date, err := time.Parse("2006-01-02", day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ECB date %q: %w", day.Time, err)
+		}
+
+		table := newRateTable(date)
+		for _, rate := range day.Rates {
+			// BGN comes from the peg rather than the rounded published rate
+			if rate.Currency == "BGN" {
+				continue
+			}
+			table.Rates[rate.Currency] = rate.Rate
+		}
+		tables = append(tables, table)
+	}
+
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("ECB feed %s contained no rates", feed)
+	}
+
+	return tables, nil
+}
@@ -0,0 +1,68 @@
+package fx
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnknownCurrency is wrapped by the error for a currency the ECB does not publish
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// BGNPerEUR is the fixed currency board peg of the lev to the euro
+const BGNPerEUR = 1.95583
+
+// minorUnits maps currencies quoted in subunits, such as London listings in
+// pence, to their main currency and the subunits per unit
+var minorUnits = map[string]struct {
+	currency string
+	per      float64
+}{
+	"GBX": {"GBP", 100},
+}
+
+// RateTable holds reference rates for one day, quoted as units of currency per 1 EUR
+type RateTable struct {
+	Date  time.Time
+	Rates map[string]float64
+}
+
+func newRateTable(date time.Time) *RateTable {
+	return &RateTable{
+		Date: date,
+		Rates: map[string]float64{
+			"EUR": 1,
+			"BGN": BGNPerEUR,
+		},
+	}
+}
+
+// Rate returns how many units of "to" one unit of "from" is worth
+func (t *RateTable) Rate(from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	fromRate, ok := t.rate(from)
+	if !ok {
+		return 0, fmt.Errorf("%w %s: no rate for %s", ErrUnknownCurrency, from, t.Date.Format("2006-01-02"))
+	}
+
+	toRate, ok := t.rate(to)
+	if !ok {
+		return 0, fmt.Errorf("%w %s: no rate for %s", ErrUnknownCurrency, to, t.Date.Format("2006-01-02"))
+	}
+
+	return toRate / fromRate, nil
+}
+
+// rate returns the units of a currency per 1 EUR, deriving subunit currencies
+func (t *RateTable) rate(currency string) (float64, bool) {
+	if minor, ok := minorUnits[currency]; ok {
+		rate, ok := t.Rates[minor.currency]
+		return rate * minor.per, ok
+	}
+
+	rate, ok := t.Rates[currency]
+	return rate, ok
+}
@@ -0,0 +1,167 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// dailyTTL bounds how long the latest daily feed is reused before refetching
+	dailyTTL = 6 * time.Hour
+
+	// maxRateAge allows weekends and TARGET holidays, when the ECB publishes no rates
+	maxRateAge = 7 * 24 * time.Hour
+)
+
+// Service answers rate queries from cached ECB reference rates
+type Service struct {
+	provider *ECBProvider
+
+	mu           sync.Mutex
+	daily        *RateTable
+	dailyFetched time.Time
+	history      map[string]*RateTable
+
+	// historyFrom and historyTo span the days covered by the history feeds
+	// fetched so far; a day in that span without a table had no rates
+	historyFrom time.Time
+	historyTo   time.Time
+}
+
+func NewService(provider *ECBProvider) *Service {
+	return &Service{
+		provider: provider,
+		history:  make(map[string]*RateTable),
+	}
+}
+
+// NewServiceFromEnv creates a service using ECB_BASE_URL, or the public ECB feed if unset
+func NewServiceFromEnv() *Service {
+	return NewService(NewECBProvider(os.Getenv("ECB_BASE_URL")))
+}
+
+// Rate returns how many units of "to" one unit of "from" was worth on asOf
+func (s *Service) Rate(ctx context.Context, from, to string, asOf time.Time) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	// The peg needs no lookup at all
+	if (from == "BGN" && to == "EUR") || (from == "EUR" && to == "BGN") {
+		return newRateTable(asOf).Rate(from, to)
+	}
+
+	table, err := s.Table(ctx, asOf)
+	if err != nil {
+		return 0, err
+	}
+
+	return table.Rate(from, to)
+}
+
+// Table returns the latest rate table published on or before asOf
+func (s *Service) Table(ctx context.Context, asOf time.Time) (*RateTable, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := truncateDay(asOf)
+	if !day.Before(truncateDay(time.Now())) {
+		return s.latest(ctx)
+	}
+
+	if table, ok := s.history[day.Format("2006-01-02")]; ok {
+		return table, nil
+	}
+
+	// Only a day known to have no rates of its own falls back to an earlier
+	// table, so a long-running process fetches new days rather than reusing
+	// whatever it cached first
+	if s.unpublished(day) {
+		if table := s.lookup(day); table != nil {
+			return table, nil
+		}
+	}
+
+	feed := FeedLast90
+	if time.Since(day) > 85*24*time.Hour {
+		feed = FeedHistorical
+	}
+
+	tables, err := s.provider.Fetch(ctx, feed)
+	if err != nil {
+		// An earlier table beats no valuation at all while the feed is down
+		if table := s.lookup(day); table != nil {
+			log.Printf("Warning: Could not fetch ECB rates for %s, using %s: %v",
+				day.Format("2006-01-02"), table.Date.Format("2006-01-02"), err)
+			return table, nil
+		}
+		return nil, fmt.Errorf("failed to fetch ECB rates: %w", err)
+	}
+	s.addHistory(tables)
+
+	if table := s.lookup(day); table != nil {
+		return table, nil
+	}
+
+	return nil, fmt.Errorf("no ECB rates published on or before %s", day.Format("2006-01-02"))
+}
+
+// addHistory caches the tables of a history feed and widens the covered span
+func (s *Service) addHistory(tables []*RateTable) {
+	for _, table := range tables {
+		s.history[table.Date.Format("2006-01-02")] = table
+	}
+
+	// Feeds are newest first
+	newest, oldest := tables[0].Date, tables[len(tables)-1].Date
+	if s.historyFrom.IsZero() || oldest.Before(s.historyFrom) {
+		s.historyFrom = oldest
+	}
+	if newest.After(s.historyTo) {
+		s.historyTo = newest
+	}
+}
+
+// unpublished reports whether the ECB is known to have published no rates on
+// day: weekends, and days inside a fetched history feed that have no table
+func (s *Service) unpublished(day time.Time) bool {
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return true
+	}
+	return !s.historyFrom.IsZero() && !day.Before(s.historyFrom) && !day.After(s.historyTo)
+}
+
+func (s *Service) latest(ctx context.Context) (*RateTable, error) {
+	if s.daily != nil && time.Since(s.dailyFetched) < dailyTTL {
+		return s.daily, nil
+	}
+
+	tables, err := s.provider.Fetch(ctx, FeedDaily)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ECB rates: %w", err)
+	}
+
+	s.daily = tables[0]
+	s.dailyFetched = time.Now()
+	s.history[s.daily.Date.Format("2006-01-02")] = s.daily
+
+	return s.daily, nil
+}
+
+// lookup finds the closest cached table on or before day
+func (s *Service) lookup(day time.Time) *RateTable {
+	for d := day; day.Sub(d) <= maxRateAge; d = d.AddDate(0, 0, -1) {
+		if table, ok := s.history[d.Format("2006-01-02")]; ok {
+			return table
+		}
+	}
+	return nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,240 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ecbStandIn serves ECB feeds built from per-day USD rates and counts requests per feed
+type ecbStandIn struct {
+	mu       sync.Mutex
+	days     map[string]float64
+	requests map[string]int
+	fail     bool
+}
+
+func newECBStandIn(t *testing.T, days map[string]float64) (*ecbStandIn, *Service) {
+	t.Helper()
+
+	stand := &ecbStandIn{days: days, requests: make(map[string]int)}
+	server := httptest.NewServer(stand)
+	t.Cleanup(server.Close)
+
+	return stand, NewService(NewECBProvider(server.URL))
+}
+
+func (e *ecbStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.requests[r.URL.Path]++
+	if e.fail {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var dates []string
+	for date := range e.days {
+		dates = append(dates, date)
+	}
+	// Newest first, as the ECB publishes them
+	sort.Sort(sort.Reverse(sort.StringSlice(dates)))
+	if r.URL.Path == FeedDaily {
+		dates = dates[:1]
+	}
+
+	var cubes strings.Builder
+	for _, date := range dates {
+		fmt.Fprintf(&cubes, `<Cube time="%s"><Cube currency="USD" rate="%g"/><Cube currency="GBP" rate="0.85"/><Cube currency="BGN" rate="1.9558"/></Cube>`, date, e.days[date])
+	}
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref"><Cube>%s</Cube></gesmes:Envelope>`, cubes.String())
+}
+
+func (e *ecbStandIn) count(feed string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.requests[feed]
+}
+
+func (e *ecbStandIn) publish(date string, usd float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.days[date] = usd
+}
+
+func (e *ecbStandIn) setFailing(fail bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fail = fail
+}
+
+func approx(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+// weekday returns the most recent Monday at least two weeks ago, so the
+// surrounding days are all in the past and inside the 90 day feed
+func weekday() time.Time {
+	day := truncateDay(time.Now()).AddDate(0, 0, -14)
+	for day.Weekday() != time.Monday {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+func TestRateConvertsThroughEuro(t *testing.T) {
+	monday := weekday()
+	_, service := newECBStandIn(t, map[string]float64{
+		monday.Format("2006-01-02"): 1.10,
+	})
+	ctx := context.Background()
+
+	rate, err := service.Rate(ctx, "USD", "BGN", monday)
+	if err != nil {
+		t.Fatalf("Rate(USD, BGN) failed: %v", err)
+	}
+	approx(t, "USD/BGN", rate, BGNPerEUR/1.10)
+
+	rate, err = service.Rate(ctx, "GBX", "GBP", monday)
+	if err != nil {
+		t.Fatalf("Rate(GBX, GBP) failed: %v", err)
+	}
+	approx(t, "GBX/GBP", rate, 0.01)
+
+	if _, err := service.Rate(ctx, "XYZ", "EUR", monday); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("Rate(XYZ, EUR) error = %v, want an unknown currency", err)
+	}
+}
+
+func TestPegNeedsNoFeed(t *testing.T) {
+	stand, service := newECBStandIn(t, map[string]float64{})
+	stand.setFailing(true)
+
+	rate, err := service.Rate(context.Background(), "EUR", "BGN", time.Now())
+	if err != nil {
+		t.Fatalf("Rate(EUR, BGN) failed: %v", err)
+	}
+	approx(t, "EUR/BGN", rate, BGNPerEUR)
+
+	// A feed that cannot be fetched says nothing about the currency
+	_, err = service.Rate(context.Background(), "USD", "EUR", time.Now())
+	if err == nil || errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("Rate(USD, EUR) error = %v, want a fetch failure", err)
+	}
+}
+
+func TestWeekendUsesFridayWithoutFetching(t *testing.T) {
+	monday := weekday()
+	friday := monday.AddDate(0, 0, -3)
+	stand, service := newECBStandIn(t, map[string]float64{
+		friday.Format("2006-01-02"): 1.08,
+		monday.Format("2006-01-02"): 1.10,
+	})
+	ctx := context.Background()
+
+	if _, err := service.Table(ctx, friday); err != nil {
+		t.Fatalf("Table(friday) failed: %v", err)
+	}
+
+	table, err := service.Table(ctx, friday.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Table(saturday) failed: %v", err)
+	}
+	if !table.Date.Equal(friday) {
+		t.Errorf("Table(saturday) = %s, want Friday's table", table.Date.Format("2006-01-02"))
+	}
+	if got := stand.count(FeedLast90); got != 1 {
+		t.Errorf("90 day feed fetched %d times, want 1", got)
+	}
+}
+
+func TestNewDayIsFetchedRatherThanWalkedBack(t *testing.T) {
+	monday := weekday()
+	tuesday := monday.AddDate(0, 0, 1)
+	stand, service := newECBStandIn(t, map[string]float64{
+		monday.Format("2006-01-02"): 1.10,
+	})
+	ctx := context.Background()
+
+	if _, err := service.Table(ctx, monday); err != nil {
+		t.Fatalf("Table(monday) failed: %v", err)
+	}
+
+	// Tuesday is published after Monday was cached, as in a long-running process
+	stand.publish(tuesday.Format("2006-01-02"), 1.12)
+
+	table, err := service.Table(ctx, tuesday)
+	if err != nil {
+		t.Fatalf("Table(tuesday) failed: %v", err)
+	}
+	if !table.Date.Equal(tuesday) {
+		t.Errorf("Table(tuesday) = %s, want Tuesday's own table", table.Date.Format("2006-01-02"))
+	}
+	if got := stand.count(FeedLast90); got != 2 {
+		t.Errorf("90 day feed fetched %d times, want 2", got)
+	}
+}
+
+func TestHolidayInsideFeedWalksBack(t *testing.T) {
+	monday := weekday()
+	wednesday := monday.AddDate(0, 0, 2)
+	stand, service := newECBStandIn(t, map[string]float64{
+		monday.Format("2006-01-02"):    1.10,
+		wednesday.Format("2006-01-02"): 1.12,
+	})
+	ctx := context.Background()
+
+	if _, err := service.Table(ctx, wednesday); err != nil {
+		t.Fatalf("Table(wednesday) failed: %v", err)
+	}
+
+	// Tuesday lies inside the fetched feed without rates, so it was a holiday
+	table, err := service.Table(ctx, monday.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Table(tuesday) failed: %v", err)
+	}
+	if !table.Date.Equal(monday) {
+		t.Errorf("Table(tuesday) = %s, want Monday's table", table.Date.Format("2006-01-02"))
+	}
+	if got := stand.count(FeedLast90); got != 1 {
+		t.Errorf("90 day feed fetched %d times, want 1", got)
+	}
+}
+
+func TestFailedFetchWalksBack(t *testing.T) {
+	monday := weekday()
+	stand, service := newECBStandIn(t, map[string]float64{
+		monday.Format("2006-01-02"): 1.10,
+	})
+	ctx := context.Background()
+
+	if _, err := service.Table(ctx, monday); err != nil {
+		t.Fatalf("Table(monday) failed: %v", err)
+	}
+
+	stand.setFailing(true)
+	table, err := service.Table(ctx, monday.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Table(tuesday) failed: %v", err)
+	}
+	if !table.Date.Equal(monday) {
+		t.Errorf("Table(tuesday) = %s, want Monday's table", table.Date.Format("2006-01-02"))
+	}
+
+	// Nothing cached within a week is an error rather than a silent guess
+	if _, err := service.Table(ctx, monday.AddDate(0, 0, 9)); err == nil {
+		t.Error("Table(a week later) succeeded while the feed is down, want an error")
+	}
+}
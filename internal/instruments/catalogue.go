@@ -0,0 +1,205 @@
+package instruments
+
+import (
+	"context"
+	"fmt"
+	"investment-tracker/internal/filecache"
+	"investment-tracker/internal/stocks"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxAge is how long the catalogue is used before it is refreshed;
+// Trading212 adds instruments daily and limits the full list to one request every 50 seconds
+const DefaultMaxAge = 24 * time.Hour
+
+// Catalogue is a locally persisted copy of the Trading212 instrument list
+type Catalogue struct {
+	mu       sync.Mutex
+	file     *filecache.File[stocks.Instrument]
+	byTicker map[string]stocks.Instrument
+	byISIN   map[string][]stocks.Instrument
+}
+
+// Open loads the catalogue file at path; a missing or unreadable file starts an empty catalogue
+func Open(path string) *Catalogue {
+	c := &Catalogue{
+		file: filecache.Open[stocks.Instrument]("instrument catalogue", "instruments", path, DefaultMaxAge),
+	}
+	c.index()
+	return c
+}
+
+// OpenFromEnv opens the catalogue at INSTRUMENTS_PATH, or next to the price cache
+// in the user cache directory, refreshing after INSTRUMENTS_MAX_AGE if set
+func OpenFromEnv() (*Catalogue, error) {
+	c := Open(filecache.Path("INSTRUMENTS_PATH", "instruments.json"))
+
+	if value := os.Getenv("INSTRUMENTS_MAX_AGE"); value != "" {
+		maxAge, err := time.ParseDuration(value)
+		if err != nil || maxAge <= 0 {
+			return nil, fmt.Errorf("invalid INSTRUMENTS_MAX_AGE %q, expected a duration such as 12h", value)
+		}
+		c.file.SetMaxAge(maxAge)
+	}
+
+	return c, nil
+}
+
+// Stale reports whether the catalogue is empty or older than its maximum age
+func (c *Catalogue) Stale() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.file.Stale()
+}
+
+// FetchedAt returns when the instrument list was last downloaded
+func (c *Catalogue) FetchedAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.file.FetchedAt()
+}
+
+// Refresh downloads the full instrument list and writes the catalogue file
+func (c *Catalogue) Refresh(ctx context.Context, client *stocks.Client) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.file.Refresh(ctx, instrumentList(client)); err != nil {
+		return err
+	}
+	c.index()
+	return nil
+}
+
+// EnsureFresh refreshes a stale catalogue. A failed refresh keeps serving
+// the previous list, which is only an error when there is none
+func (c *Catalogue) EnsureFresh(ctx context.Context, client *stocks.Client) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	refreshed, err := c.file.EnsureFresh(ctx, instrumentList(client))
+	if refreshed {
+		c.index()
+	}
+	return err
+}
+
+func instrumentList(client *stocks.Client) func(ctx context.Context) ([]stocks.Instrument, error) {
+	return func(ctx context.Context) ([]stocks.Instrument, error) {
+		if client == nil {
+			return nil, fmt.Errorf("no Trading212 client to refresh the instrument catalogue")
+		}
+		return client.GetInstruments(ctx)
+	}
+}
+
+func (c *Catalogue) index() {
+	list := c.file.Items()
+	c.byTicker = make(map[string]stocks.Instrument, len(list))
+	c.byISIN = make(map[string][]stocks.Instrument)
+	for _, instrument := range list {
+		c.byTicker[instrument.Ticker] = instrument
+		if instrument.ISIN != "" {
+			isin := strings.ToUpper(instrument.ISIN)
+			c.byISIN[isin] = append(c.byISIN[isin], instrument)
+		}
+	}
+}
+
+// Lookup returns the instrument with a Trading212 ticker such as AAPL_US_EQ
+func (c *Catalogue) Lookup(ticker string) (stocks.Instrument, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	instrument, ok := c.byTicker[ticker]
+	return instrument, ok
+}
+
+// ByISIN returns every listing of an ISIN, one per exchange and currency
+func (c *Catalogue) ByISIN(isin string) []stocks.Instrument {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]stocks.Instrument(nil), c.byISIN[strings.ToUpper(isin)]...)
+}
+
+// Search returns the instruments whose name, short name or ticker contains
+// the query, ignoring case, ordered by ticker
+func (c *Catalogue) Search(query string) []stocks.Instrument {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+
+	var matches []stocks.Instrument
+	for _, instrument := range c.byTicker {
+		if strings.Contains(strings.ToLower(instrument.Name), query) ||
+			strings.Contains(strings.ToLower(instrument.ShortName), query) ||
+			strings.Contains(strings.ToLower(instrument.Ticker), query) {
+			matches = append(matches, instrument)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Ticker < matches[j].Ticker
+	})
+	return matches
+}
+
+// Resolve finds the one instrument meant by a ticker, an ISIN, a short name
+// or a name, in that order, and fails when the query is ambiguous
+func (c *Catalogue) Resolve(query string) (stocks.Instrument, error) {
+	if instrument, ok := c.Lookup(query); ok {
+		return instrument, nil
+	}
+
+	if listings := c.ByISIN(query); len(listings) > 0 {
+		if len(listings) > 1 {
+			return stocks.Instrument{}, ambiguous(query, listings)
+		}
+		return listings[0], nil
+	}
+
+	matches := c.Search(query)
+
+	// An exact short name or name wins over partial matches
+	var exact []stocks.Instrument
+	for _, instrument := range matches {
+		if strings.EqualFold(instrument.ShortName, query) || strings.EqualFold(instrument.Name, query) {
+			exact = append(exact, instrument)
+		}
+	}
+	if len(exact) > 0 {
+		matches = exact
+	}
+
+	switch len(matches) {
+	case 0:
+		return stocks.Instrument{}, fmt.Errorf("no instrument matches %q", query)
+	case 1:
+		return matches[0], nil
+	default:
+		return stocks.Instrument{}, ambiguous(query, matches)
+	}
+}
+
+func ambiguous(query string, matches []stocks.Instrument) error {
+	const shown = 5
+
+	var tickers []string
+	for i, instrument := range matches {
+		if i == shown {
+			tickers = append(tickers, fmt.Sprintf("and %d more", len(matches)-shown))
+			break
+		}
+		tickers = append(tickers, instrument.Ticker)
+	}
+	return fmt.Errorf("%q matches several instruments: %s", query, strings.Join(tickers, ", "))
+}
@@ -0,0 +1,161 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"investment-tracker/internal/filecache"
+	"investment-tracker/internal/stocks"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultMaxAge is how long schedules are used before they are refreshed;
+// Trading212 only publishes the coming days of each schedule
+const DefaultMaxAge = 24 * time.Hour
+
+// ErrUnknownSchedule is returned for a working schedule the calendar does not have
+var ErrUnknownSchedule = errors.New("unknown working schedule")
+
+// ErrOutOfRange is returned for times the published schedule does not cover
+var ErrOutOfRange = errors.New("time outside the published schedule")
+
+// Calendar answers market hours questions from the Trading212 exchange schedules
+type Calendar struct {
+	mu        sync.Mutex
+	file      *filecache.File[stocks.Exchange]
+	schedules map[int]schedule
+}
+
+// schedule is a working schedule with its events in time order
+type schedule struct {
+	exchange string
+	events   []stocks.TimeEvent
+}
+
+// Open loads the calendar file at path; a missing or unreadable file starts an empty calendar
+func Open(path string) *Calendar {
+	c := &Calendar{
+		file: filecache.Open[stocks.Exchange]("market calendar", "exchanges", path, DefaultMaxAge),
+	}
+	c.index()
+	return c
+}
+
+// OpenFromEnv opens the calendar at MARKET_CALENDAR_PATH, or in the user cache directory
+func OpenFromEnv() *Calendar {
+	return Open(filecache.Path("MARKET_CALENDAR_PATH", "exchanges.json"))
+}
+
+// EnsureFresh downloads the schedules when they are missing or older than a
+// day. A failed refresh keeps the previous schedules, which is only an error when there are none
+func (c *Calendar) EnsureFresh(ctx context.Context, client *stocks.Client) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	refreshed, err := c.file.EnsureFresh(ctx, client.GetExchanges)
+	if refreshed {
+		c.index()
+	}
+	return err
+}
+
+func (c *Calendar) index() {
+	c.schedules = make(map[int]schedule)
+	for _, exchange := range c.file.Items() {
+		for _, working := range exchange.WorkingSchedules {
+			events := append([]stocks.TimeEvent(nil), working.TimeEvents...)
+			sort.SliceStable(events, func(i, j int) bool {
+				return events[i].Date.Before(events[j].Date)
+			})
+			c.schedules[working.ID] = schedule{exchange: exchange.Name, events: events}
+		}
+	}
+}
+
+// Exchange returns the name of the exchange a working schedule belongs to
+func (c *Calendar) Exchange(scheduleID int) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.schedules[scheduleID].exchange
+}
+
+// IsOpen reports whether the regular session of a working schedule is open
+// at a time; pre-market, after-hours and lunch breaks count as closed
+func (c *Calendar) IsOpen(scheduleID int, at time.Time) (bool, error) {
+	events, err := c.events(scheduleID)
+	if err != nil {
+		return false, err
+	}
+
+	// The last event at or before the time sets the session state
+	i := sort.Search(len(events), func(i int) bool {
+		return events[i].Date.After(at)
+	})
+	if i == 0 || i == len(events) {
+		return false, fmt.Errorf("%w: schedule %d covers %s to %s", ErrOutOfRange, scheduleID,
+			events[0].Date.Format(time.RFC3339), events[len(events)-1].Date.Format(time.RFC3339))
+	}
+
+	switch events[i-1].Type {
+	case stocks.EventOpen, stocks.EventBreakEnd:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// NextOpen returns when the regular session of a working schedule next opens after a time
+func (c *Calendar) NextOpen(scheduleID int, after time.Time) (time.Time, error) {
+	return c.next(scheduleID, after, stocks.EventOpen)
+}
+
+// NextClose returns when the regular session of a working schedule next closes after a time
+func (c *Calendar) NextClose(scheduleID int, after time.Time) (time.Time, error) {
+	return c.next(scheduleID, after, stocks.EventClose)
+}
+
+func (c *Calendar) next(scheduleID int, after time.Time, eventType string) (time.Time, error) {
+	events, err := c.events(scheduleID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, event := range events {
+		if event.Type == eventType && event.Date.After(after) {
+			return event.Date, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%w: no %s after %s in schedule %d", ErrOutOfRange, eventType, after.Format(time.RFC3339), scheduleID)
+}
+
+func (c *Calendar) events(scheduleID int) ([]stocks.TimeEvent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	schedule, ok := c.schedules[scheduleID]
+	if !ok || len(schedule.events) == 0 {
+		return nil, fmt.Errorf("%w %d", ErrUnknownSchedule, scheduleID)
+	}
+	return schedule.events, nil
+}
+
+// InstrumentOpen reports whether an instrument's market is open at a time
+func (c *Calendar) InstrumentOpen(instrument stocks.Instrument, at time.Time) (bool, error) {
+	open, err := c.IsOpen(instrument.WorkingScheduleID, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to check market hours of %s: %w", instrument.Ticker, err)
+	}
+	return open, nil
+}
+
+// InstrumentNextClose returns when an instrument's market next closes after a time
+func (c *Calendar) InstrumentNextClose(instrument stocks.Instrument, after time.Time) (time.Time, error) {
+	closeAt, err := c.NextClose(instrument.WorkingScheduleID, after)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to find the next close of %s: %w", instrument.Ticker, err)
+	}
+	return closeAt, nil
+}
@@ -2,6 +2,7 @@ package notifications
 
 import (
 	"fmt"
+	"investment-tracker/internal/valuation"
 	"net/smtp"
 	"os"
 )
@@ -66,20 +67,42 @@ func (e *EmailNotifier) SendEmail(subject, body string) error {
 	return nil
 }
 
-func (e *EmailNotifier) SendInvestmentUpdate(totalBGN, stocksBGN, cryptoBGN, bullionBGN float64) error {
+func (e *EmailNotifier) SendInvestmentUpdate(v *valuation.Valuation) error {
 	subject := "💰 Daily Investment Update"
+	if !v.Complete() {
+		subject += " (incomplete)"
+	}
+
 	body := fmt.Sprintf(
 		"Good morning! Here's your daily portfolio update:\n\n"+
 			"📊 Portfolio Breakdown:\n"+
-			"🏦 Trading212: %.2f BGN\n"+
-			"₿ Crypto: %.2f BGN\n"+
-			"🥇 Bullion: %.2f BGN\n\n"+
-			"💎 Total Investment Worth: %.2f BGN\n\n"+
-			"📈 Have a great day!\n\n"+
-			"---\n"+
-			"This is an automated daily update from your Investment Tracker",
-		stocksBGN, cryptoBGN, bullionBGN, totalBGN,
+			"🏦 Trading212: %s\n"+
+			"₿ Crypto: %s\n"+
+			"🥇 Bullion: %s\n"+
+			"%s\n"+
+			"💎 Total Investment Worth: %s\n\n",
+		formatComponent(v, valuation.ClassStocks),
+		formatComponent(v, valuation.ClassCrypto),
+		formatComponent(v, valuation.ClassBullion),
+		manualComponents(v, "📌 %s: %s\n"),
+		formatTotal(v),
 	)
 
+	if accounts := accountBreakdown(v, plain); accounts != "" {
+		body += accounts + "\n\n"
+	}
+	if positions := positionBreakdown(v, plain); positions != "" {
+		body += positions + "\n\n"
+	}
+	if pies := pieBreakdown(v, plain); pies != "" {
+		body += pies + "\n\n"
+	}
+	if note := statusNotes(v, plain); note != "" {
+		body += note + "\n\n"
+	}
+	body += "📈 Have a great day!\n\n" +
+		"---\n" +
+		"This is an automated daily update from your Investment Tracker"
+
 	return e.SendEmail(subject, body)
-}
\ No newline at end of file
+}
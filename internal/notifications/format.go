@@ -0,0 +1,168 @@
+package notifications
+
+import (
+	"fmt"
+	"investment-tracker/internal/valuation"
+	"sort"
+	"strings"
+)
+
+// maxPositions bounds the positions listed in an update, largest first
+const maxPositions = 5
+
+var classLabels = map[valuation.AssetClass]string{
+	valuation.ClassStocks:   "Trading212",
+	valuation.ClassCrypto:   "Crypto",
+	valuation.ClassBullion:  "Bullion",
+	valuation.ClassEquities: "Stocks",
+	valuation.ClassSavings:  "Savings",
+}
+
+// escaper protects interpolated names, such as pie names or tickers like
+// AAPL_US_EQ, from being read as markup by a channel
+type escaper func(string) string
+
+// plain leaves names as they are, for channels without markup
+func plain(s string) string {
+	return s
+}
+
+// manualClasses are listed only when the holdings file has entries for them
+var manualClasses = []valuation.AssetClass{valuation.ClassEquities, valuation.ClassSavings}
+
+// manualComponents renders the subtotal of each hand-tracked class in the
+// valuation with format, which takes the class label and value
+func manualComponents(v *valuation.Valuation, format string) string {
+	var components string
+	for _, class := range manualClasses {
+		if len(v.LinesFor(class)) == 0 && v.ClassStatus(class) == valuation.StatusOK {
+			continue
+		}
+		components += fmt.Sprintf(format, classLabels[class], formatComponent(v, class))
+	}
+	return components
+}
+
+// formatComponent renders a class subtotal, marking classes whose sources failed
+// so that a missing source is not mistaken for a drop in value
+func formatComponent(v *valuation.Valuation, class valuation.AssetClass) string {
+	value := fmt.Sprintf("%.2f %s", v.Subtotal(class), v.Currency)
+	if performance, ok := v.ClassPerformance(class); ok && performance.Cost > 0 {
+		value += fmt.Sprintf(" (%+.2f%%)", performance.PnLPercent())
+	}
+
+	switch v.ClassStatus(class) {
+	case valuation.StatusFailed:
+		if len(v.LinesFor(class)) == 0 {
+			return "unavailable"
+		}
+		return value + " (incomplete)"
+	case valuation.StatusStale:
+		return value + " (stale)"
+	}
+
+	return value
+}
+
+// formatTotal renders the total, marking it as partial when a source failed
+func formatTotal(v *valuation.Valuation) string {
+	total := fmt.Sprintf("%.2f %s", v.Total, v.Currency)
+	if !v.Complete() {
+		total += " (partial)"
+	}
+	return total
+}
+
+// statusNotes lists the components that could not be valued and the prices
+// that are not live, or "" when everything is fresh
+func statusNotes(v *valuation.Valuation, escape escaper) string {
+	var notes []string
+
+	if failed := v.Failed(); len(failed) > 0 {
+		var names []string
+		for _, source := range failed {
+			names = append(names, fmt.Sprintf("%s (%s)", classLabels[source.Class], escape(source.Source)))
+		}
+		notes = append(notes, "⚠️ Missing data: "+strings.Join(names, ", "))
+	}
+
+	for _, line := range v.Stale() {
+		notes = append(notes, fmt.Sprintf("⏳ %s price from %s", escape(line.Symbol), valuation.FormatAge(line.Timestamp)))
+	}
+
+	return strings.Join(notes, "\n")
+}
+
+// accountBreakdown lists the value of each Trading212 account, or "" with a single account
+func accountBreakdown(v *valuation.Valuation, escape escaper) string {
+	accounts := v.LinesFor(valuation.ClassStocks)
+	if len(accounts) < 2 {
+		return ""
+	}
+
+	lines := []string{"🏦 Accounts:"}
+	for _, account := range accounts {
+		line := fmt.Sprintf("- %s: %.2f %s", escape(account.Symbol), account.Value, v.Currency)
+		if account.Stale {
+			line += " (stale)"
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// positionBreakdown lists the largest Trading212 positions by instrument name,
+// with their type, trading currency and return, or "" without positions
+func positionBreakdown(v *valuation.Valuation, escape escaper) string {
+	if len(v.Positions) == 0 {
+		return ""
+	}
+
+	positions := append([]valuation.Position(nil), v.Positions...)
+	sort.SliceStable(positions, func(i, j int) bool {
+		return positions[i].Value > positions[j].Value
+	})
+
+	lines := []string{"📄 Top positions:"}
+	for i, position := range positions {
+		if i == maxPositions {
+			lines = append(lines, fmt.Sprintf("- and %d more", len(positions)-maxPositions))
+			break
+		}
+
+		name := position.Name
+		if name == "" {
+			name = position.Ticker
+		}
+		line := fmt.Sprintf("- %s (%s, %s): %.2f %s", escape(name), strings.ToLower(position.Type), position.Currency, position.Value, v.Currency)
+		if cost := position.Value - position.PnL; cost > 0 {
+			line += fmt.Sprintf(" (%+.2f%%)", position.PnL/cost*100)
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// pieBreakdown lists the value and return of each Trading212 pie, or "" without pies
+func pieBreakdown(v *valuation.Valuation, escape escaper) string {
+	if len(v.Pies) == 0 {
+		return ""
+	}
+
+	lines := []string{"🥧 Pies:"}
+	for _, pie := range v.Pies {
+		name := pie.Name
+		if pie.Account != "" {
+			name += " (" + pie.Account + ")"
+		}
+		line := fmt.Sprintf("- %s: %.2f %s", escape(name), pie.Value, v.Currency)
+		if pie.Invested > 0 {
+			line += fmt.Sprintf(" (%+.2f%%)", pie.Result/pie.Invested*100)
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
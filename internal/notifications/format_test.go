@@ -0,0 +1,37 @@
+package notifications
+
+import (
+	"investment-tracker/internal/valuation"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTelegramEscapesInterpolatedNames(t *testing.T) {
+	v := valuation.New("BGN")
+	v.Add(valuation.Line{Class: valuation.ClassStocks, Symbol: "my_isa", Value: 100, Stale: true, Timestamp: time.Now()})
+	v.Add(valuation.Line{Class: valuation.ClassStocks, Symbol: "invest", Value: 200})
+	v.Pies = []valuation.Pie{{Name: "*Tech* [growth]", Value: 50}}
+	v.Positions = []valuation.Position{{Ticker: "AAPL_US_EQ", Type: "STOCK", Currency: "USD", Value: 80}}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"accounts", accountBreakdown(v, escapeMarkdown), `- my\_isa: 100.00 BGN (stale)`},
+		{"pies", pieBreakdown(v, escapeMarkdown), `- \*Tech\* \[growth]: 50.00 BGN`},
+		{"positions", positionBreakdown(v, escapeMarkdown), `- AAPL\_US\_EQ (stock, USD): 80.00 BGN`},
+		{"notes", statusNotes(v, escapeMarkdown), `⏳ my\_isa price from`},
+	}
+	for _, tt := range tests {
+		if !strings.Contains(tt.got, tt.want) {
+			t.Errorf("%s = %q, want it to contain %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Plain text channels show names as they are
+	if got := positionBreakdown(v, plain); !strings.Contains(got, "- AAPL_US_EQ (stock") {
+		t.Errorf("plain positions = %q, want the ticker unescaped", got)
+	}
+}
@@ -1,15 +1,20 @@
 package notifications
 
 import (
+	"context"
 	"fmt"
+	"investment-tracker/internal/valuation"
 	"log"
 	"time"
 )
 
+// ValueFunc produces the portfolio valuation to notify about
+type ValueFunc func(ctx context.Context) (*valuation.Valuation, error)
+
 type Scheduler struct {
-	service          *NotificationService
-	done             chan bool
-	onNotificationSent func(total, stocks, crypto, bullion float64)
+	service            *NotificationService
+	done               chan bool
+	onNotificationSent func(v *valuation.Valuation)
 }
 
 func NewScheduler(service *NotificationService) *Scheduler {
@@ -19,17 +24,17 @@ func NewScheduler(service *NotificationService) *Scheduler {
 	}
 }
 
-func (s *Scheduler) SetOnNotificationSent(callback func(total, stocks, crypto, bullion float64)) {
+func (s *Scheduler) SetOnNotificationSent(callback func(v *valuation.Valuation)) {
 	s.onNotificationSent = callback
 }
 
-func (s *Scheduler) Start(hour, minute int, getPortfolioValues func() (total, stocks, crypto, bullion float64, err error)) {
+func (s *Scheduler) Start(hour, minute int, value ValueFunc) {
 	log.Printf("Starting daily notification scheduler for %02d:%02d", hour, minute)
 
 	// Calculate time until next notification
 	now := time.Now()
 	nextRun := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
-	
+
 	// If the time has already passed today, schedule for tomorrow
 	if now.After(nextRun) {
 		nextRun = nextRun.Add(24 * time.Hour)
@@ -39,17 +44,17 @@ func (s *Scheduler) Start(hour, minute int, getPortfolioValues func() (total, st
 
 	// Wait until the first scheduled time
 	timer := time.NewTimer(nextRun.Sub(now))
-	
+
 	go func() {
 		for {
 			select {
 			case <-timer.C:
 				// Send notification
-				s.sendScheduledNotification(getPortfolioValues)
-				
+				s.sendScheduledNotification(value)
+
 				// Schedule next notification (24 hours from now)
 				timer.Reset(24 * time.Hour)
-				
+
 			case <-s.done:
 				timer.Stop()
 				return
@@ -58,25 +63,48 @@ func (s *Scheduler) Start(hour, minute int, getPortfolioValues func() (total, st
 	}()
 }
 
-func (s *Scheduler) sendScheduledNotification(getPortfolioValues func() (total, stocks, crypto, bullion float64, err error)) {
+// NextRunFunc returns the next time to notify after a point in time, such as
+// the next market close
+type NextRunFunc func(after time.Time) (time.Time, error)
+
+// retryDelay is how long StartAt waits before asking again for a next run it could not find
+const retryDelay = time.Hour
+
+// StartAt sends a notification at each time returned by next, e.g. after every
+// market close rather than at a fixed time of day
+func (s *Scheduler) StartAt(next NextRunFunc, value ValueFunc) {
+	go func() {
+		for {
+			wait := retryDelay
+			nextRun, err := next(time.Now())
+			if err != nil {
+				log.Printf("Error scheduling notification, retrying in %s: %v", retryDelay, err)
+			} else {
+				log.Printf("Next notification scheduled for: %v", nextRun)
+				wait = time.Until(nextRun)
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+				if err == nil {
+					s.sendScheduledNotification(value)
+				}
+			case <-s.done:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) sendScheduledNotification(value ValueFunc) {
 	log.Println("Sending scheduled investment notification...")
-	
-	total, stocks, crypto, bullion, err := getPortfolioValues()
-	if err != nil {
-		log.Printf("Error getting portfolio values: %v", err)
-		return
-	}
 
-	err = s.service.SendDailyUpdate(total, stocks, crypto, bullion)
-	if err != nil {
+	if err := s.send(value); err != nil {
 		log.Printf("Error sending notification: %v", err)
 	} else {
 		log.Println("Successfully sent scheduled notification")
-		
-		// Call the callback to save to database
-		if s.onNotificationSent != nil {
-			s.onNotificationSent(total, stocks, crypto, bullion)
-		}
 	}
 }
 
@@ -85,13 +113,26 @@ func (s *Scheduler) Stop() {
 }
 
 // Helper function to run once immediately (for testing)
-func (s *Scheduler) SendNow(getPortfolioValues func() (total, stocks, crypto, bullion float64, err error)) error {
+func (s *Scheduler) SendNow(value ValueFunc) error {
 	log.Println("Sending immediate investment notification...")
-	
-	total, stocks, crypto, bullion, err := getPortfolioValues()
+
+	return s.send(value)
+}
+
+func (s *Scheduler) send(value ValueFunc) error {
+	v, err := value(context.Background())
 	if err != nil {
 		return fmt.Errorf("error getting portfolio values: %w", err)
 	}
 
-	return s.service.SendDailyUpdate(total, stocks, crypto, bullion)
-}
\ No newline at end of file
+	if err := s.service.SendDailyUpdate(v); err != nil {
+		return err
+	}
+
+	// Call the callback to save to database
+	if s.onNotificationSent != nil {
+		s.onNotificationSent(v)
+	}
+
+	return nil
+}
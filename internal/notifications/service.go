@@ -2,6 +2,7 @@ package notifications
 
 import (
 	"fmt"
+	"investment-tracker/internal/valuation"
 	"log"
 	"os"
 	"strings"
@@ -21,7 +22,7 @@ func NewNotificationService() *NotificationService {
 	}
 }
 
-func (ns *NotificationService) SendDailyUpdate(totalBGN, stocksBGN, cryptoBGN, bullionBGN float64) error {
+func (ns *NotificationService) SendDailyUpdate(v *valuation.Valuation) error {
 	// Get notification methods from environment variable
 	methods := os.Getenv("NOTIFICATION_METHODS")
 	if methods == "" {
@@ -33,22 +34,22 @@ func (ns *NotificationService) SendDailyUpdate(totalBGN, stocksBGN, cryptoBGN, b
 
 	for _, method := range methodList {
 		method = strings.TrimSpace(method)
-		
+
 		switch method {
 		case "telegram":
-			if err := ns.telegram.SendInvestmentUpdate(totalBGN, stocksBGN, cryptoBGN, bullionBGN); err != nil {
+			if err := ns.telegram.SendInvestmentUpdate(v); err != nil {
 				errors = append(errors, fmt.Sprintf("Telegram: %v", err))
 			} else {
 				log.Printf("Successfully sent Telegram notification")
 			}
 		case "sms":
-			if err := ns.twilio.SendInvestmentUpdate(totalBGN, stocksBGN, cryptoBGN, bullionBGN); err != nil {
+			if err := ns.twilio.SendInvestmentUpdate(v); err != nil {
 				errors = append(errors, fmt.Sprintf("SMS: %v", err))
 			} else {
 				log.Printf("Successfully sent SMS notification")
 			}
 		case "email":
-			if err := ns.email.SendInvestmentUpdate(totalBGN, stocksBGN, cryptoBGN, bullionBGN); err != nil {
+			if err := ns.email.SendInvestmentUpdate(v); err != nil {
 				errors = append(errors, fmt.Sprintf("Email: %v", err))
 			} else {
 				log.Printf("Successfully sent email notification")
@@ -67,5 +68,17 @@ func (ns *NotificationService) SendDailyUpdate(totalBGN, stocksBGN, cryptoBGN, b
 
 func (ns *NotificationService) TestNotifications() error {
 	// Test with dummy data
-	return ns.SendDailyUpdate(5000.0, 2000.0, 2000.0, 1000.0)
-}
\ No newline at end of file
+	return ns.SendDailyUpdate(SampleValuation())
+}
+
+// SampleValuation returns a valuation with dummy data for testing notifications
+func SampleValuation() *valuation.Valuation {
+	v := valuation.New(valuation.BaseCurrency())
+	v.Add(valuation.Line{Class: valuation.ClassStocks, Symbol: "Trading212", Amount: 1, Price: 2000, Value: 2000, Currency: v.Currency, Source: "sample"})
+	v.Add(valuation.Line{Class: valuation.ClassCrypto, Symbol: "BTC", Amount: 0.02, Price: 100000, Value: 2000, Currency: v.Currency, Source: "sample", Cost: 1600, HasCost: true})
+	v.Add(valuation.Line{Class: valuation.ClassBullion, Symbol: "XAU", Amount: 0.2, Price: 5000, Value: 1000, Currency: v.Currency, Source: "sample"})
+	v.Add(valuation.Line{Class: valuation.ClassSavings, Symbol: "Deposit", Amount: 1020, Price: 1, Value: 1020, Currency: v.Currency, Source: "sample", Cost: 1000, HasCost: true})
+	v.Positions = append(v.Positions, valuation.Position{Ticker: "AAPL_US_EQ", Name: "Apple", Type: "STOCK", Currency: "USD", Quantity: 4, Price: 400, Value: 1600, PnL: 200})
+	v.Pies = append(v.Pies, valuation.Pie{ID: 1, Name: "Sample pie", Invested: 1000, Value: 1100, Result: 100})
+	return v
+}
@@ -2,6 +2,7 @@ package notifications
 
 import (
 	"fmt"
+	"investment-tracker/internal/valuation"
 	"net/http"
 	"net/url"
 	"os"
@@ -35,7 +36,7 @@ func (t *TwilioNotifier) SendSMS(message string) error {
 	data.Set("Body", message)
 
 	url := fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json", t.AccountSID)
-	
+
 	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(data.Encode()))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
@@ -58,16 +59,34 @@ func (t *TwilioNotifier) SendSMS(message string) error {
 	return nil
 }
 
-func (t *TwilioNotifier) SendInvestmentUpdate(totalBGN, stocksBGN, cryptoBGN, bullionBGN float64) error {
+func (t *TwilioNotifier) SendInvestmentUpdate(v *valuation.Valuation) error {
 	message := fmt.Sprintf(
 		"💰 Daily Investment Update\n\n"+
-			"Trading212: %.2f BGN\n"+
-			"Crypto: %.2f BGN\n"+
-			"Bullion: %.2f BGN\n\n"+
-			"Total: %.2f BGN\n\n"+
-			"Have a great day! 📈",
-		stocksBGN, cryptoBGN, bullionBGN, totalBGN,
+			"Trading212: %s\n"+
+			"Crypto: %s\n"+
+			"Bullion: %s\n"+
+			"%s\n"+
+			"Total: %s\n\n",
+		formatComponent(v, valuation.ClassStocks),
+		formatComponent(v, valuation.ClassCrypto),
+		formatComponent(v, valuation.ClassBullion),
+		manualComponents(v, "%s: %s\n"),
+		formatTotal(v),
 	)
 
+	if accounts := accountBreakdown(v, plain); accounts != "" {
+		message += accounts + "\n\n"
+	}
+	if positions := positionBreakdown(v, plain); positions != "" {
+		message += positions + "\n\n"
+	}
+	if pies := pieBreakdown(v, plain); pies != "" {
+		message += pies + "\n\n"
+	}
+	if note := statusNotes(v, plain); note != "" {
+		message += note + "\n\n"
+	}
+	message += "Have a great day! 📈"
+
 	return t.SendSMS(message)
-}
\ No newline at end of file
+}
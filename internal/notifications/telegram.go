@@ -4,11 +4,22 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"investment-tracker/internal/valuation"
 	"io"
 	"net/http"
 	"os"
+	"strings"
 )
 
+// markdownEscaper escapes the characters legacy Telegram Markdown reads as markup
+var markdownEscaper = strings.NewReplacer("_", "\\_", "*", "\\*", "`", "\\`", "[", "\\[")
+
+// escapeMarkdown keeps a name from breaking the message; unbalanced markup
+// makes Telegram reject the whole message
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
 type TelegramNotifier struct {
 	BotToken string
 	ChatID   string
@@ -63,16 +74,34 @@ func (t *TelegramNotifier) SendMessage(message string) error {
 	return nil
 }
 
-func (t *TelegramNotifier) SendInvestmentUpdate(totalBGN, stocksBGN, cryptoBGN, bullionBGN float64) error {
+func (t *TelegramNotifier) SendInvestmentUpdate(v *valuation.Valuation) error {
 	message := fmt.Sprintf(
 		"*Daily Investment Update*\n\n"+
-			"- Trading212: 	`%.2f BGN`\n"+
-			"- Crypto: 		`%.2f BGN`\n"+
-			"- Bullion: 	`%.2f BGN`\n\n"+
-			" *Total: %.2f BGN*\n\n",
-		stocksBGN, cryptoBGN, bullionBGN, totalBGN,
+			"- Trading212: 	`%s`\n"+
+			"- Crypto: 		`%s`\n"+
+			"- Bullion: 	`%s`\n"+
+			"%s\n"+
+			" *Total: %s*\n\n",
+		formatComponent(v, valuation.ClassStocks),
+		formatComponent(v, valuation.ClassCrypto),
+		formatComponent(v, valuation.ClassBullion),
+		manualComponents(v, "- %s: 	`%s`\n"),
+		formatTotal(v),
 	)
 
+	if accounts := accountBreakdown(v, escapeMarkdown); accounts != "" {
+		message += accounts + "\n\n"
+	}
+	if positions := positionBreakdown(v, escapeMarkdown); positions != "" {
+		message += positions + "\n\n"
+	}
+	if pies := pieBreakdown(v, escapeMarkdown); pies != "" {
+		message += pies + "\n\n"
+	}
+	if note := statusNotes(v, escapeMarkdown); note != "" {
+		message += note + "\n"
+	}
+
 	return t.SendMessage(message)
 }
 
@@ -106,11 +135,11 @@ func (t *TelegramNotifier) GetChatInfo() error {
 	}
 
 	url := fmt.Sprintf("https://api.telegram.org/bot%s/getChat", t.BotToken)
-	
+
 	data := map[string]string{
 		"chat_id": t.ChatID,
 	}
-	
+
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("failed to marshal chat request: %w", err)
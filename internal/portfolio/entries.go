@@ -0,0 +1,108 @@
+package portfolio
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Sections of the holdings file, in the order they are listed
+const (
+	SectionCrypto  = "crypto"
+	SectionBullion = "bullion"
+	SectionStocks  = "stocks"
+	SectionSavings = "savings"
+)
+
+var Sections = []string{SectionCrypto, SectionBullion, SectionStocks, SectionSavings}
+
+// Entry is one holding of a section, such as crypto BTC, with its value as
+// it is written in the holdings file
+type Entry struct {
+	Section string
+	Name    string
+	Value   json.RawMessage
+}
+
+// Entries splits the holdings into one entry per holding, by section and name
+func (h *Holdings) Entries() ([]Entry, error) {
+	var entries []Entry
+	var err error
+
+	for _, section := range Sections {
+		switch section {
+		case SectionCrypto:
+			entries, err = appendEntries(entries, section, h.Crypto)
+		case SectionBullion:
+			entries, err = appendEntries(entries, section, h.Bullion)
+		case SectionStocks:
+			entries, err = appendEntries(entries, section, h.Stocks)
+		case SectionSavings:
+			entries, err = appendEntries(entries, section, h.Savings)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// Entry returns the holding of a section by name, and whether there is one
+func (h *Holdings) Entry(section, name string) (Entry, bool, error) {
+	entries, err := h.Entries()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	for _, entry := range entries {
+		if entry.Section == section && entry.Name == name {
+			return entry, true, nil
+		}
+	}
+	return Entry{}, false, nil
+}
+
+func appendEntries[V any](entries []Entry, section string, values map[string]V) ([]Entry, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value, err := json.Marshal(values[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s %s: %w", section, name, err)
+		}
+		entries = append(entries, Entry{Section: section, Name: name, Value: value})
+	}
+	return entries, nil
+}
+
+// HoldingsFromEntries assembles holdings from their entries, validating them
+// as a holdings file would be
+func HoldingsFromEntries(entries []Entry) (*Holdings, error) {
+	sections := make(map[string]map[string]json.RawMessage)
+	for _, entry := range entries {
+		if sections[entry.Section] == nil {
+			sections[entry.Section] = make(map[string]json.RawMessage)
+		}
+		sections[entry.Section][entry.Name] = entry.Value
+	}
+
+	document := map[string]interface{}{"version": Version}
+	for section, values := range sections {
+		document[section] = values
+	}
+
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode holdings: %w", err)
+	}
+
+	doc := ParseHoldings("", data)
+	if err := doc.Err(); err != nil {
+		return nil, err
+	}
+	return doc.Holdings, nil
+}
@@ -0,0 +1,285 @@
+package portfolio
+
+import (
+	"fmt"
+	"investment-tracker/internal/bullion"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Transaction types of the ledger
+const (
+	TxBuy           = "buy"
+	TxSell          = "sell"
+	TxTransfer      = "transfer"
+	TxFee           = "fee"
+	TxStakingReward = "staking-reward"
+	TxAirdrop       = "airdrop"
+)
+
+var TransactionTypes = []string{TxBuy, TxSell, TxTransfer, TxFee, TxStakingReward, TxAirdrop}
+
+// LedgerSections are the sections whose holdings the ledger can derive
+var LedgerSections = []string{SectionCrypto, SectionBullion}
+
+// quantityEpsilon absorbs rounding when lots are drawn down to nothing
+const quantityEpsilon = 1e-12
+
+// Transaction is one entry of the ledger. Quantity is in units of the asset:
+// bought, sold, received, moved by a transfer or paid as a fee. Price is per
+// unit in Currency: paid, received, or the market value of a reward or
+// airdrop, which becomes its cost and may be zero. Fee is in Currency, except
+// for transfers, whose network fee is paid in units of the asset
+type Transaction struct {
+	ID       string
+	Time     time.Time
+	Type     string
+	Section  string
+	Asset    string
+	Quantity float64
+	Price    float64
+	Currency string
+	Fee      float64
+	From     string
+	To       string
+	Note     string
+}
+
+// String describes a transaction for error messages
+func (t Transaction) String() string {
+	description := fmt.Sprintf("%s %s %g %s %s", t.Time.Format(DateLayout), t.Type, t.Quantity, t.Section, t.Asset)
+	if t.ID != "" {
+		description += " (" + t.ID + ")"
+	}
+	return description
+}
+
+// Validate checks a transaction on its own
+func (t Transaction) Validate() error {
+	if t.Time.IsZero() {
+		return fmt.Errorf("a transaction needs a date")
+	}
+	if t.Time.After(time.Now()) {
+		return fmt.Errorf("transaction date %s is in the future", t.Time.Format(DateLayout))
+	}
+	if !contains(TransactionTypes, t.Type) {
+		return fmt.Errorf("unknown transaction type %q, expected one of %s", t.Type, strings.Join(TransactionTypes, ", "))
+	}
+	if !contains(LedgerSections, t.Section) {
+		return fmt.Errorf("unsupported section %q, expected one of %s", t.Section, strings.Join(LedgerSections, ", "))
+	}
+	if t.Asset == "" {
+		return fmt.Errorf("a transaction needs an asset")
+	}
+	if t.Section == SectionCrypto && t.Asset != strings.ToUpper(t.Asset) {
+		return fmt.Errorf("crypto symbols are upper case, e.g. %s", strings.ToUpper(t.Asset))
+	}
+	if _, ok := bullion.Metals[t.Asset]; t.Section == SectionBullion && !ok {
+		return fmt.Errorf("unsupported metal %q, expected one of %s", t.Asset, strings.Join(sortedMetals(), ", "))
+	}
+	if t.Quantity < 0 || t.Price < 0 || t.Fee < 0 {
+		return fmt.Errorf("quantity, price and fee must not be negative")
+	}
+
+	switch t.Type {
+	case TxBuy, TxSell, TxStakingReward, TxAirdrop:
+		if t.Quantity == 0 {
+			return fmt.Errorf("a %s needs a quantity", t.Type)
+		}
+		// Received units become lots, which carry the currency of their cost
+		if t.Currency == "" {
+			return fmt.Errorf("a %s needs the currency of its price", t.Type)
+		}
+	case TxTransfer:
+		if t.Quantity == 0 {
+			return fmt.Errorf("a transfer needs a quantity")
+		}
+		if t.Fee > t.Quantity {
+			return fmt.Errorf("the transfer fee exceeds the quantity moved")
+		}
+	case TxFee:
+		if t.Quantity == 0 && t.Fee == 0 {
+			return fmt.Errorf("a fee needs a quantity of the asset or an amount in a currency")
+		}
+		if t.Fee > 0 && t.Currency == "" {
+			return fmt.Errorf("a fee amount needs its currency")
+		}
+	}
+
+	if t.Currency != "" && !currencyCode.MatchString(t.Currency) {
+		return fmt.Errorf("invalid currency %q, expected a code such as EUR", t.Currency)
+	}
+	return nil
+}
+
+// Realization is a disposal of units: a sale, or units paid as a fee, which
+// realise their cost as a loss. Proceeds are net of fees, in Currency, and
+// Lots are the cost of the units disposed of
+type Realization struct {
+	Time     time.Time
+	Type     string
+	Section  string
+	Asset    string
+	Quantity float64
+	Proceeds float64
+	Currency string
+	Lots     []Lot
+}
+
+// Books are the holdings and realisations derived from a ledger
+type Books struct {
+	Crypto   map[string]Asset
+	Bullion  map[string]Asset
+	Realized []Realization
+}
+
+// Section returns the derived holdings of a section
+func (b *Books) Section(section string) map[string]Asset {
+	if section == SectionBullion {
+		return b.Bullion
+	}
+	return b.Crypto
+}
+
+// Replay derives holdings and realised P&L from transactions. Lots are
+// drawn down first in, first out; it fails on a transaction that disposes
+// of more than is held at that point
+func Replay(transactions []Transaction) (*Books, error) {
+	ordered := append([]Transaction(nil), transactions...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Time.Before(ordered[j].Time)
+	})
+
+	lots := make(map[string]map[string][]Lot)
+	for _, section := range LedgerSections {
+		lots[section] = make(map[string][]Lot)
+	}
+
+	books := &Books{}
+	for _, t := range ordered {
+		if err := t.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid transaction %s: %w", t, err)
+		}
+		held := lots[t.Section][t.Asset]
+		day := Date{calendarDay(t.Time)}
+
+		switch t.Type {
+		case TxBuy, TxStakingReward, TxAirdrop:
+			held = append(held, Lot{
+				Quantity: t.Quantity,
+				Date:     day,
+				UnitCost: t.Price,
+				Currency: t.Currency,
+				Fees:     t.Fee,
+			})
+
+		case TxSell:
+			remaining, disposed, err := drawDown(held, t.Quantity)
+			if err != nil {
+				return nil, fmt.Errorf("cannot apply %s: %w", t, err)
+			}
+			held = remaining
+			books.Realized = append(books.Realized, realization(t, t.Quantity, t.Quantity*t.Price-t.Fee, disposed))
+
+		case TxTransfer:
+			// Moving units between wallets changes nothing but the network fee
+			if t.Fee > 0 {
+				remaining, disposed, err := drawDown(held, t.Fee)
+				if err != nil {
+					return nil, fmt.Errorf("cannot apply %s: %w", t, err)
+				}
+				held = remaining
+				books.Realized = append(books.Realized, realization(t, t.Fee, 0, disposed))
+			}
+
+		case TxFee:
+			var disposed []Lot
+			if t.Quantity > 0 {
+				remaining, drawn, err := drawDown(held, t.Quantity)
+				if err != nil {
+					return nil, fmt.Errorf("cannot apply %s: %w", t, err)
+				}
+				held, disposed = remaining, drawn
+			}
+			books.Realized = append(books.Realized, realization(t, t.Quantity, -t.Fee, disposed))
+		}
+
+		lots[t.Section][t.Asset] = held
+	}
+
+	books.Crypto = assets(lots[SectionCrypto])
+	books.Bullion = assets(lots[SectionBullion])
+	return books, nil
+}
+
+// drawDown takes a quantity from the oldest lots, splitting fees pro rata,
+// and returns the lots left and those taken
+func drawDown(lots []Lot, quantity float64) ([]Lot, []Lot, error) {
+	var held float64
+	for _, lot := range lots {
+		held += lot.Quantity
+	}
+	if quantity > held+quantityEpsilon {
+		return nil, nil, fmt.Errorf("only %g held", held)
+	}
+
+	var taken []Lot
+	remaining := append([]Lot(nil), lots...)
+	for quantity > quantityEpsilon && len(remaining) > 0 {
+		lot := remaining[0]
+		if lot.Quantity <= quantity+quantityEpsilon {
+			taken = append(taken, lot)
+			quantity -= lot.Quantity
+			remaining = remaining[1:]
+			continue
+		}
+
+		share := quantity / lot.Quantity
+		part := lot
+		part.Quantity = quantity
+		part.Fees = lot.Fees * share
+		taken = append(taken, part)
+
+		remaining[0].Quantity -= quantity
+		remaining[0].Fees -= part.Fees
+		quantity = 0
+	}
+
+	return remaining, taken, nil
+}
+
+func realization(t Transaction, quantity, proceeds float64, disposed []Lot) Realization {
+	return Realization{
+		Time:     t.Time,
+		Type:     t.Type,
+		Section:  t.Section,
+		Asset:    t.Asset,
+		Quantity: quantity,
+		Proceeds: proceeds,
+		Currency: t.Currency,
+		Lots:     disposed,
+	}
+}
+
+// assets keeps the assets that are still held
+func assets(lots map[string][]Lot) map[string]Asset {
+	held := make(map[string]Asset)
+	for name, remaining := range lots {
+		asset := Asset{Lots: remaining}
+		if len(remaining) > 0 && math.Abs(asset.Quantity()) > quantityEpsilon {
+			held[name] = asset
+		}
+	}
+	return held
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+package portfolio
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(n int) time.Time {
+	return time.Date(2024, time.March, n, 12, 0, 0, 0, time.UTC)
+}
+
+func buy(n int, asset string, quantity, price, fee float64) Transaction {
+	return Transaction{Time: day(n), Type: TxBuy, Section: SectionCrypto, Asset: asset, Quantity: quantity, Price: price, Currency: "EUR", Fee: fee}
+}
+
+func sell(n int, asset string, quantity, price, fee float64) Transaction {
+	return Transaction{Time: day(n), Type: TxSell, Section: SectionCrypto, Asset: asset, Quantity: quantity, Price: price, Currency: "EUR", Fee: fee}
+}
+
+func cost(lots []Lot) float64 {
+	var total float64
+	for _, lot := range lots {
+		total += lot.Cost()
+	}
+	return total
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// holding is the quantity and cost basis an asset should be left with
+type holding struct {
+	quantity float64
+	cost     float64
+	lots     int
+}
+
+// disposal is a realisation Replay should produce
+type disposal struct {
+	quantity float64
+	proceeds float64
+	cost     float64
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		err          string
+		crypto       map[string]holding
+		bullion      map[string]holding
+		realized     []disposal
+	}{
+		{
+			name: "sale splits a lot and its fees",
+			transactions: []Transaction{
+				buy(1, "BTC", 1, 100, 10),
+				buy(2, "BTC", 1, 200, 20),
+				sell(3, "BTC", 1.5, 300, 15),
+			},
+			crypto:   map[string]holding{"BTC": {quantity: 0.5, cost: 110, lots: 1}},
+			realized: []disposal{{quantity: 1.5, proceeds: 435, cost: 220}},
+		},
+		{
+			name: "transactions are replayed by date",
+			transactions: []Transaction{
+				sell(3, "BTC", 1, 300, 0),
+				buy(2, "BTC", 1, 200, 0),
+				buy(1, "BTC", 1, 100, 0),
+			},
+			crypto:   map[string]holding{"BTC": {quantity: 1, cost: 200, lots: 1}},
+			realized: []disposal{{quantity: 1, proceeds: 300, cost: 100}},
+		},
+		{
+			name: "selling everything drops the asset despite rounding",
+			transactions: []Transaction{
+				buy(1, "ETH", 0.1, 1000, 0),
+				buy(2, "ETH", 0.2, 1000, 0),
+				sell(3, "ETH", 0.3, 2000, 0),
+			},
+			crypto:   map[string]holding{},
+			realized: []disposal{{quantity: 0.3, proceeds: 600, cost: 300}},
+		},
+		{
+			name: "overselling fails",
+			transactions: []Transaction{
+				buy(1, "BTC", 1, 100, 0),
+				sell(2, "BTC", 1.5, 300, 0),
+			},
+			err: "only 1 held",
+		},
+		{
+			name: "selling before buying fails",
+			transactions: []Transaction{
+				sell(1, "BTC", 1, 300, 0),
+				buy(2, "BTC", 1, 100, 0),
+			},
+			err: "only 0 held",
+		},
+		{
+			name: "transfer disposes of its network fee only",
+			transactions: []Transaction{
+				buy(1, "BTC", 2, 100, 0),
+				{Time: day(2), Type: TxTransfer, Section: SectionCrypto, Asset: "BTC", Quantity: 1, Fee: 0.01, From: "exchange", To: "ledger"},
+			},
+			crypto:   map[string]holding{"BTC": {quantity: 1.99, cost: 199, lots: 1}},
+			realized: []disposal{{quantity: 0.01, proceeds: 0, cost: 1}},
+		},
+		{
+			name: "transfer without a fee changes nothing",
+			transactions: []Transaction{
+				buy(1, "BTC", 2, 100, 0),
+				{Time: day(2), Type: TxTransfer, Section: SectionCrypto, Asset: "BTC", Quantity: 2, From: "exchange", To: "ledger"},
+			},
+			crypto: map[string]holding{"BTC": {quantity: 2, cost: 200, lots: 1}},
+		},
+		{
+			name: "transfer fee above the holding fails",
+			transactions: []Transaction{
+				buy(1, "BTC", 0.001, 100, 0),
+				{Time: day(2), Type: TxTransfer, Section: SectionCrypto, Asset: "BTC", Quantity: 1, Fee: 0.01},
+			},
+			err: "only 0.001 held",
+		},
+		{
+			name: "currency-only fee leaves the lots alone",
+			transactions: []Transaction{
+				buy(1, "BTC", 1, 100, 0),
+				{Time: day(2), Type: TxFee, Section: SectionCrypto, Asset: "BTC", Fee: 5, Currency: "EUR"},
+			},
+			crypto:   map[string]holding{"BTC": {quantity: 1, cost: 100, lots: 1}},
+			realized: []disposal{{quantity: 0, proceeds: -5, cost: 0}},
+		},
+		{
+			name: "fee paid in units realises their cost",
+			transactions: []Transaction{
+				buy(1, "BTC", 1, 100, 0),
+				{Time: day(2), Type: TxFee, Section: SectionCrypto, Asset: "BTC", Quantity: 0.1},
+			},
+			crypto:   map[string]holding{"BTC": {quantity: 0.9, cost: 90, lots: 1}},
+			realized: []disposal{{quantity: 0.1, proceeds: 0, cost: 10}},
+		},
+		{
+			name: "zero-cost staking rewards are drawn down after older lots",
+			transactions: []Transaction{
+				buy(1, "ETH", 1, 1000, 0),
+				{Time: day(2), Type: TxStakingReward, Section: SectionCrypto, Asset: "ETH", Quantity: 0.1, Currency: "EUR"},
+				sell(3, "ETH", 1.05, 2000, 0),
+			},
+			crypto:   map[string]holding{"ETH": {quantity: 0.05, cost: 0, lots: 1}},
+			realized: []disposal{{quantity: 1.05, proceeds: 2100, cost: 1000}},
+		},
+		{
+			name: "reward without a currency is invalid",
+			transactions: []Transaction{
+				{Time: day(1), Type: TxStakingReward, Section: SectionCrypto, Asset: "ETH", Quantity: 0.1},
+			},
+			err: "needs the currency of its price",
+		},
+		{
+			name: "bullion is kept apart from crypto",
+			transactions: []Transaction{
+				{Time: day(1), Type: TxBuy, Section: SectionBullion, Asset: "XAU", Quantity: 2, Price: 2000, Currency: "EUR"},
+				buy(1, "BTC", 1, 100, 0),
+			},
+			crypto:  map[string]holding{"BTC": {quantity: 1, cost: 100, lots: 1}},
+			bullion: map[string]holding{"XAU": {quantity: 2, cost: 4000, lots: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			books, err := Replay(tt.transactions)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Replay failed: %v", err)
+			}
+
+			checkHoldings(t, SectionCrypto, books.Crypto, tt.crypto)
+			checkHoldings(t, SectionBullion, books.Bullion, tt.bullion)
+
+			if len(books.Realized) != len(tt.realized) {
+				t.Fatalf("got %d realisations, want %d", len(books.Realized), len(tt.realized))
+			}
+			for i, want := range tt.realized {
+				got := books.Realized[i]
+				if !near(got.Quantity, want.quantity) || !near(got.Proceeds, want.proceeds) || !near(cost(got.Lots), want.cost) {
+					t.Errorf("realisation %d = %g units for %g at a cost of %g, want %g for %g at %g",
+						i, got.Quantity, got.Proceeds, cost(got.Lots), want.quantity, want.proceeds, want.cost)
+				}
+			}
+		})
+	}
+}
+
+func checkHoldings(t *testing.T, section string, got map[string]Asset, want map[string]holding) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("%s holds %d assets, want %d", section, len(got), len(want))
+	}
+	for name, w := range want {
+		asset, ok := got[name]
+		if !ok {
+			t.Errorf("%s %s not held", section, name)
+			continue
+		}
+		if !near(asset.Quantity(), w.quantity) || !near(cost(asset.Lots), w.cost) || len(asset.Lots) != w.lots {
+			t.Errorf("%s %s = %g in %d lots costing %g, want %g in %d lots costing %g",
+				section, name, asset.Quantity(), len(asset.Lots), cost(asset.Lots), w.quantity, w.lots, w.cost)
+		}
+	}
+}
+
+func TestDrawDownLeavesLotsUntouched(t *testing.T) {
+	lots := []Lot{
+		{Quantity: 1, UnitCost: 100, Fees: 10},
+		{Quantity: 2, UnitCost: 200, Fees: 20},
+	}
+
+	remaining, taken, err := drawDown(lots, 2)
+	if err != nil {
+		t.Fatalf("drawDown failed: %v", err)
+	}
+
+	if len(taken) != 2 || !near(taken[1].Quantity, 1) || !near(taken[1].Fees, 10) {
+		t.Errorf("taken = %+v, want the first lot and half of the second", taken)
+	}
+	if len(remaining) != 1 || !near(remaining[0].Quantity, 1) || !near(remaining[0].Fees, 10) {
+		t.Errorf("remaining = %+v, want half of the second lot", remaining)
+	}
+
+	// The caller's lots are not changed by a partial draw
+	if lots[1].Quantity != 2 || lots[1].Fees != 20 {
+		t.Errorf("input lot changed to %+v", lots[1])
+	}
+}
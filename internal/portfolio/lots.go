@@ -0,0 +1,126 @@
+package portfolio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DateLayout is how lot dates are written in the holdings file
+const DateLayout = "2006-01-02"
+
+// Date is a calendar day, such as the day a lot was acquired
+type Date struct {
+	time.Time
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte(`""`), nil
+	}
+	return json.Marshal(d.Format(DateLayout))
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid date %s, expected %s", data, DateLayout)
+	}
+	if value == "" {
+		d.Time = time.Time{}
+		return nil
+	}
+
+	t, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return fmt.Errorf("invalid date %q, expected %s", value, DateLayout)
+	}
+	d.Time = t
+	return nil
+}
+
+// Lot is one acquisition of an asset; UnitCost and Fees are in Currency
+type Lot struct {
+	Quantity float64 `json:"quantity"`
+	Date     Date    `json:"date"`
+	UnitCost float64 `json:"unit_cost"`
+	Currency string  `json:"currency"`
+	Fees     float64 `json:"fees,omitempty"`
+}
+
+// Cost returns what the lot cost including fees, in its currency
+func (l Lot) Cost() float64 {
+	return l.Quantity*l.UnitCost + l.Fees
+}
+
+// Asset is the holding of one symbol: either a plain amount, as in version 1
+// files, or the lots it was bought in, which also give its cost basis
+type Asset struct {
+	Amount float64
+	Lots   []Lot
+}
+
+// Quantity returns the amount held
+func (a Asset) Quantity() float64 {
+	if len(a.Lots) == 0 {
+		return a.Amount
+	}
+
+	var quantity float64
+	for _, lot := range a.Lots {
+		quantity += lot.Quantity
+	}
+	return quantity
+}
+
+// HasLots reports whether the asset is held as lots, and so has a cost basis
+func (a Asset) HasLots() bool {
+	return len(a.Lots) > 0
+}
+
+// HeldOn returns the lots acquired by the end of a day, and whether any
+// were. A plain amount has no dates and is always held
+func (a Asset) HeldOn(day time.Time) (Asset, bool) {
+	if len(a.Lots) == 0 {
+		return a, true
+	}
+
+	end := calendarDay(day).AddDate(0, 0, 1)
+
+	var held Asset
+	for _, lot := range a.Lots {
+		if lot.Date.IsZero() || lot.Date.Before(end) {
+			held.Lots = append(held.Lots, lot)
+		}
+	}
+	return held, len(held.Lots) > 0
+}
+
+// MarshalJSON writes a plain amount as a number and lots as a list
+func (a Asset) MarshalJSON() ([]byte, error) {
+	if len(a.Lots) == 0 {
+		return json.Marshal(a.Amount)
+	}
+	return json.Marshal(a.Lots)
+}
+
+// UnmarshalJSON reads either a plain amount or a list of lots
+func (a *Asset) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var lots []Lot
+		if err := json.Unmarshal(data, &lots); err != nil {
+			return err
+		}
+		*a = Asset{Lots: lots}
+		return nil
+	}
+
+	var amount float64
+	if err := json.Unmarshal(data, &amount); err != nil {
+		return fmt.Errorf("expected an amount or a list of lots, got %s", data)
+	}
+	*a = Asset{Amount: amount}
+	return nil
+}
@@ -0,0 +1,92 @@
+package portfolio
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Compounding schedules for savings interest
+const (
+	CompoundingNone      = "none"
+	CompoundingDaily     = "daily"
+	CompoundingMonthly   = "monthly"
+	CompoundingQuarterly = "quarterly"
+	CompoundingAnnually  = "annually"
+)
+
+// compoundingPeriods is how often each schedule credits interest per year
+var compoundingPeriods = map[string]float64{
+	CompoundingDaily:     365,
+	CompoundingMonthly:   12,
+	CompoundingQuarterly: 4,
+	CompoundingAnnually:  1,
+}
+
+// Equity is a stock or fund held outside Trading212, valued at a price kept
+// by hand in Currency. It is held as a plain quantity or as lots
+type Equity struct {
+	Name      string  `json:"name,omitempty"`
+	Currency  string  `json:"currency"`
+	Price     float64 `json:"price"`
+	PriceDate Date    `json:"price_date,omitempty"`
+	Quantity  float64 `json:"quantity,omitempty"`
+	Lots      []Lot   `json:"lots,omitempty"`
+}
+
+// Asset returns the holding of the equity
+func (e Equity) Asset() Asset {
+	return Asset{Amount: e.Quantity, Lots: e.Lots}
+}
+
+// Savings is a savings or deposit account. Interest at InterestRate percent a
+// year accrues on Balance from StartDate until MaturityDate, if set
+type Savings struct {
+	Currency     string  `json:"currency"`
+	Balance      float64 `json:"balance"`
+	InterestRate float64 `json:"interest_rate,omitempty"`
+	Compounding  string  `json:"compounding,omitempty"`
+	StartDate    Date    `json:"start_date,omitempty"`
+	MaturityDate Date    `json:"maturity_date,omitempty"`
+}
+
+// OpenOn reports whether the account existed on a day
+func (s Savings) OpenOn(day time.Time) bool {
+	return s.StartDate.IsZero() || !calendarDay(day).Before(s.StartDate.Time)
+}
+
+// ValueOn returns the balance with the interest accrued by a day. Interest is
+// credited at the end of each compounding period and accrues simply in
+// between; without a start date the balance is taken as it is
+func (s Savings) ValueOn(day time.Time) (float64, error) {
+	periods, compounds := compoundingPeriods[s.Compounding]
+	if !compounds && s.Compounding != "" && s.Compounding != CompoundingNone {
+		return 0, fmt.Errorf("unknown compounding %q", s.Compounding)
+	}
+	if s.StartDate.IsZero() || s.InterestRate == 0 {
+		return s.Balance, nil
+	}
+
+	end := calendarDay(day)
+	if !s.MaturityDate.IsZero() && end.After(s.MaturityDate.Time) {
+		end = s.MaturityDate.Time
+	}
+	if !end.After(s.StartDate.Time) {
+		return s.Balance, nil
+	}
+
+	years := end.Sub(s.StartDate.Time).Hours() / 24 / 365
+	rate := s.InterestRate / 100
+
+	if !compounds {
+		return s.Balance * (1 + rate*years), nil
+	}
+
+	credited := math.Floor(years * periods)
+	compounded := s.Balance * math.Pow(1+rate/periods, credited)
+	return compounded * (1 + rate*(years-credited/periods)), nil
+}
+
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -3,95 +3,77 @@ package portfolio
 import (
 	"encoding/json"
 	"fmt"
-	"investment-tracker/internal/bullion"
-	"investment-tracker/internal/crypto"
-	"io/ioutil"
+	"os"
 )
 
-// func sumCryptoHoldings()
+// Version is the current holdings schema. Version 1 files give a plain amount
+// per asset, version 2 files a list of lots; both load
+const Version = 2
 
 type Holdings struct {
-	Crypto  map[string]float64 `json:"crypto"`
-	Bullion map[string]float64 `json:"bullion"`
+	Version int                `json:"version,omitempty"`
+	Crypto  map[string]Asset   `json:"crypto"`
+	Bullion map[string]Asset   `json:"bullion"`
+	Stocks  map[string]Equity  `json:"stocks"`
+	Savings map[string]Savings `json:"savings"`
 }
 
+// LoadHoldings reads and validates a holdings file, returning a
+// ValidationError that lists every problem when it does not match the schema
 func LoadHoldings(path string) (*Holdings, error) {
-
-	bytes, err := ioutil.ReadFile(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read holdings file: %w", err)
 	}
 
-	var holdings Holdings
-
-	err = json.Unmarshal([]byte(bytes), &holdings)
-	if err != nil {
-		fmt.Println("Error decoding JSON:", err)
+	doc := ParseHoldings(path, data)
+	if err := doc.Err(); err != nil {
 		return nil, err
 	}
 
-	return &holdings, nil
+	return doc.Holdings, nil
 }
 
-func SumHoldingsTotal(h *Holdings) (float64, error) {
-
-	var sum float64
-
-	for symbol, amount := range h.Crypto {
-		price, err := crypto.GetCryptoPrice(symbol)
-
-		if err != nil {
-			return 0, fmt.Errorf("could not sum crypto networth %s", err)
-		}
-
-		totalPricePerCrypto := amount * price
-
-		sum += totalPricePerCrypto
-
+// EncodeHoldings writes holdings as a holdings file of the current version,
+// with empty sections as {} so that it validates when loaded again
+func EncodeHoldings(h *Holdings) ([]byte, error) {
+	file := *h
+	file.Version = Version
+	if file.Crypto == nil {
+		file.Crypto = map[string]Asset{}
 	}
-
-	fmt.Printf("Crypto Networth: %v", sum*1.7346)
-
-	for metal, amount := range h.Bullion {
-
-		priceResponse, err := bullion.GetBullionPrices(metal, "USD")
-		if err != nil {
-			return 0, fmt.Errorf("could not sum bullion networth %s", err)
-		}
-
-		sum += amount * priceResponse.Price
+	if file.Bullion == nil {
+		file.Bullion = map[string]Asset{}
 	}
-
-	return sum, nil
-}
-
-func SumCryptoHoldings(h *Holdings) (float64, error) {
-	var sum float64
-
-	for symbol, amount := range h.Crypto {
-		price, err := crypto.GetCryptoPrice(symbol)
-		if err != nil {
-			return 0, fmt.Errorf("could not sum crypto networth %s", err)
-		}
-
-		totalPricePerCrypto := amount * price
-		sum += totalPricePerCrypto
+	if file.Stocks == nil {
+		file.Stocks = map[string]Equity{}
+	}
+	if file.Savings == nil {
+		file.Savings = map[string]Savings{}
 	}
 
-	return sum, nil
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode holdings: %w", err)
+	}
+	return append(data, '\n'), nil
 }
 
-func SumBullionHoldings(h *Holdings) (float64, error) {
-	var sum float64
-
-	for metal, amount := range h.Bullion {
-		priceResponse, err := bullion.GetBullionPrices(metal, "USD")
-		if err != nil {
-			return 0, fmt.Errorf("could not sum bullion networth %s", err)
-		}
+// SaveHoldings validates holdings and writes them to a temporary file that is
+// renamed into place, so a failed write never leaves a half-written file
+func SaveHoldings(path string, h *Holdings) error {
+	data, err := EncodeHoldings(h)
+	if err != nil {
+		return err
+	}
+	if err := ParseHoldings(path, data).Err(); err != nil {
+		return err
+	}
 
-		sum += amount * priceResponse.Price
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write holdings file: %w", err)
 	}
 
-	return sum, nil
+	return os.Rename(tmp, path)
 }
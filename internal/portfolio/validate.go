@@ -0,0 +1,516 @@
+package portfolio
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"investment-tracker/internal/bullion"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Issue is one problem in a holdings file, at the line and column it was found
+type Issue struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Path == "" {
+		return fmt.Sprintf("%d:%d: %s", i.Line, i.Column, i.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", i.Line, i.Column, i.Path, i.Message)
+}
+
+// ValidationError lists every issue found in a holdings file
+type ValidationError struct {
+	File   string
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	// Holdings assembled from stored entries have no file to point into
+	if e.File == "" {
+		lines := []string{"invalid holdings:"}
+		for _, issue := range e.Issues {
+			lines = append(lines, "  "+issue.Path+": "+issue.Message)
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	lines := []string{fmt.Sprintf("invalid holdings file %s:", e.File)}
+	for _, issue := range e.Issues {
+		lines = append(lines, "  "+e.File+":"+issue.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Document is a holdings file checked against the schema. Holdings is set
+// only when there were no issues
+type Document struct {
+	File     string
+	Holdings *Holdings
+	Issues   []Issue
+
+	data []byte
+	root *node
+}
+
+// ParseHoldings decodes a holdings file strictly: unknown sections and fields,
+// wrong types, negative amounts, unsupported metals and malformed dates or
+// currencies are all reported with their position
+func ParseHoldings(file string, data []byte) *Document {
+	d := &Document{File: file, data: data}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	root, err := parseNode(dec, data)
+	if err == nil {
+		if _, extra := dec.Token(); extra != io.EOF {
+			d.issue(dec.InputOffset(), "", "unexpected data after the holdings object")
+			return d
+		}
+	}
+	if err != nil {
+		var syntax *json.SyntaxError
+		offset := int64(len(data))
+		if errors.As(err, &syntax) {
+			offset = syntax.Offset
+		}
+		d.issue(offset, "", "invalid JSON: %v", err)
+		return d
+	}
+
+	d.root = root
+	d.checkHoldings(root)
+	if len(d.Issues) > 0 {
+		return d
+	}
+
+	var holdings Holdings
+	if err := json.Unmarshal(data, &holdings); err != nil {
+		d.issue(0, "", "%v", err)
+		return d
+	}
+	d.Holdings = &holdings
+	return d
+}
+
+// Err returns a ValidationError listing the issues, or nil when there are none
+func (d *Document) Err() error {
+	if len(d.Issues) == 0 {
+		return nil
+	}
+	return &ValidationError{File: d.File, Issues: d.Issues}
+}
+
+// Report adds an issue at a field, given as its section and keys, such as
+// "crypto", "BTC"; checks that need a price provider use it
+func (d *Document) Report(path []string, format string, args ...interface{}) {
+	offset := int64(0)
+	n := d.root
+	for _, key := range path {
+		if n == nil {
+			break
+		}
+		n = n.field(key)
+	}
+	if n != nil {
+		offset = n.offset
+	}
+	d.issue(offset, joinPath(path), format, args...)
+}
+
+// joinPath writes a path as the checks do, such as crypto.BTC[0].currency
+func joinPath(path []string) string {
+	var joined string
+	for i, key := range path {
+		if _, err := strconv.Atoi(key); err == nil && i > 0 {
+			joined += "[" + key + "]"
+			continue
+		}
+		if i > 0 {
+			joined += "."
+		}
+		joined += key
+	}
+	return joined
+}
+
+func (d *Document) issue(offset int64, path, format string, args ...interface{}) {
+	if offset > int64(len(d.data)) {
+		offset = int64(len(d.data))
+	}
+	before := d.data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+
+	d.Issues = append(d.Issues, Issue{
+		Path:    path,
+		Line:    line,
+		Column:  column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// node is a JSON value and the offset it starts at
+type node struct {
+	offset int64
+	kind   string
+
+	keys       []string
+	fields     map[string]*node
+	duplicates []string
+	repeated   []int64
+	items      []*node
+	number     float64
+	text       string
+}
+
+const (
+	kindObject = "an object"
+	kindArray  = "a list"
+	kindString = "a string"
+	kindNumber = "a number"
+	kindBool   = "true or false"
+	kindNull   = "null"
+)
+
+func (n *node) field(key string) *node {
+	if n.kind == kindArray {
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(n.items) {
+			return nil
+		}
+		return n.items[i]
+	}
+	return n.fields[key]
+}
+
+// parseNode reads the next value from the decoder, recording where it starts
+func parseNode(dec *json.Decoder, data []byte) (*node, error) {
+	n := &node{offset: valueStart(data, dec.InputOffset())}
+
+	token, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil, &json.SyntaxError{Offset: int64(len(data))}
+		}
+		return nil, err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		if value == '{' {
+			n.kind = kindObject
+			n.fields = make(map[string]*node)
+			for dec.More() {
+				keyStart := valueStart(data, dec.InputOffset())
+				token, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key := token.(string)
+
+				child, err := parseNode(dec, data)
+				if err != nil {
+					return nil, err
+				}
+				if _, duplicate := n.fields[key]; duplicate {
+					// The first value is kept and the repeat reported where it appears
+					n.duplicates = append(n.duplicates, key)
+					n.repeated = append(n.repeated, keyStart)
+					continue
+				}
+				child.offset = keyStart
+				n.keys = append(n.keys, key)
+				n.fields[key] = child
+			}
+		} else {
+			n.kind = kindArray
+			for dec.More() {
+				child, err := parseNode(dec, data)
+				if err != nil {
+					return nil, err
+				}
+				n.items = append(n.items, child)
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		n.kind, n.text = kindString, value
+	case float64:
+		n.kind, n.number = kindNumber, value
+	case bool:
+		n.kind = kindBool
+	case nil:
+		n.kind = kindNull
+	}
+
+	return n, nil
+}
+
+// valueStart skips the whitespace and separators before the next token
+func valueStart(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// fieldCheck validates one field of an object
+type fieldCheck func(d *Document, path string, n *node)
+
+var lotFields = map[string]fieldCheck{
+	"quantity":  positive,
+	"date":      pastDate,
+	"unit_cost": nonNegative,
+	"currency":  currency,
+	"fees":      nonNegative,
+}
+
+var equityFields = map[string]fieldCheck{
+	"name":       text,
+	"currency":   currency,
+	"price":      positive,
+	"price_date": pastDate,
+	"quantity":   nonNegative,
+	"lots":       lots,
+}
+
+var savingsFields = map[string]fieldCheck{
+	"currency":      currency,
+	"balance":       nonNegative,
+	"interest_rate": nonNegative,
+	"compounding":   compounding,
+	"start_date":    date,
+	"maturity_date": date,
+}
+
+func (d *Document) checkHoldings(root *node) {
+	if !d.expect("", root, kindObject) {
+		return
+	}
+
+	d.duplicate("", root)
+	for _, key := range root.keys {
+		n := root.fields[key]
+
+		switch key {
+		case "version":
+			if d.expect(key, n, kindNumber) && (n.number != float64(int(n.number)) || n.number < 1 || n.number > Version) {
+				d.issue(n.offset, key, "unsupported version %g, expected 1 to %d", n.number, Version)
+			}
+		case "crypto":
+			d.eachEntry(key, n, func(path, symbol string, n *node) {
+				if symbol != strings.ToUpper(symbol) {
+					d.issue(n.offset, path, "crypto symbols are upper case, e.g. %s", strings.ToUpper(symbol))
+				}
+				d.checkAsset(path, n)
+			})
+		case "bullion":
+			d.eachEntry(key, n, func(path, metal string, n *node) {
+				if _, ok := bullion.Metals[metal]; !ok {
+					d.issue(n.offset, path, "unsupported metal %q, expected one of %s", metal, strings.Join(sortedMetals(), ", "))
+				}
+				d.checkAsset(path, n)
+			})
+		case "stocks":
+			d.eachEntry(key, n, func(path, _ string, n *node) {
+				if !d.checkObject(path, n, equityFields, "currency", "price") {
+					return
+				}
+				if quantity := n.fields["quantity"]; quantity != nil && n.fields["lots"] != nil {
+					d.issue(quantity.offset, path+".quantity", "give either a quantity or lots, not both")
+				}
+			})
+		case "savings":
+			d.eachEntry(key, n, func(path, _ string, n *node) {
+				if !d.checkObject(path, n, savingsFields, "currency", "balance") {
+					return
+				}
+				start, maturity := n.fields["start_date"], n.fields["maturity_date"]
+				if start != nil && maturity != nil && start.text != "" && maturity.text != "" && maturity.text <= start.text {
+					d.issue(maturity.offset, path+".maturity_date", "maturity date %s is not after the start date %s", maturity.text, start.text)
+				}
+			})
+		default:
+			d.issue(n.offset, key, "unknown section, expected crypto, bullion, stocks or savings")
+		}
+	}
+}
+
+// eachEntry checks that a section is an object and visits each of its entries
+func (d *Document) eachEntry(section string, n *node, visit func(path, key string, n *node)) {
+	if !d.expect(section, n, kindObject) {
+		return
+	}
+	d.duplicate(section, n)
+	for _, key := range n.keys {
+		path := section + "." + key
+		entry := n.fields[key]
+		if key == "" {
+			d.issue(entry.offset, path, "empty name")
+			continue
+		}
+		visit(path, key, entry)
+	}
+}
+
+// checkAsset checks a crypto or bullion holding: an amount or a list of lots
+func (d *Document) checkAsset(path string, n *node) {
+	switch n.kind {
+	case kindNumber:
+		nonNegative(d, path, n)
+	case kindArray:
+		lots(d, path, n)
+	default:
+		d.issue(n.offset, path, "expected an amount or a list of lots, got %s", n.kind)
+	}
+}
+
+// checkObject checks an object's fields against a schema and its required
+// fields, reporting whether it was an object at all
+func (d *Document) checkObject(path string, n *node, fields map[string]fieldCheck, required ...string) bool {
+	if !d.expect(path, n, kindObject) {
+		return false
+	}
+	d.duplicate(path, n)
+
+	for _, key := range n.keys {
+		field := n.fields[key]
+		fieldPath := path + "." + key
+		check, ok := fields[key]
+		if !ok {
+			d.issue(field.offset, fieldPath, "unknown field, expected one of %s", strings.Join(fieldNames(fields), ", "))
+			continue
+		}
+		check(d, fieldPath, field)
+	}
+
+	for _, key := range required {
+		if _, ok := n.fields[key]; !ok {
+			d.issue(n.offset, path, "missing %s", key)
+		}
+	}
+	return true
+}
+
+func (d *Document) expect(path string, n *node, kind string) bool {
+	if n.kind != kind {
+		d.issue(n.offset, path, "expected %s, got %s", kind, n.kind)
+		return false
+	}
+	return true
+}
+
+// duplicate reports the keys given more than once in an object
+func (d *Document) duplicate(path string, n *node) {
+	for i, key := range n.duplicates {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		d.issue(n.repeated[i], keyPath, "duplicate key, the first value is used")
+	}
+}
+
+func lots(d *Document, path string, n *node) {
+	if !d.expect(path, n, kindArray) {
+		return
+	}
+	if len(n.items) == 0 {
+		d.issue(n.offset, path, "empty list of lots")
+	}
+	for i, lot := range n.items {
+		d.checkObject(fmt.Sprintf("%s[%d]", path, i), lot, lotFields, "quantity", "unit_cost", "currency")
+	}
+}
+
+func text(d *Document, path string, n *node) {
+	d.expect(path, n, kindString)
+}
+
+func nonNegative(d *Document, path string, n *node) {
+	if d.expect(path, n, kindNumber) && n.number < 0 {
+		d.issue(n.offset, path, "negative amount %g", n.number)
+	}
+}
+
+func positive(d *Document, path string, n *node) {
+	if d.expect(path, n, kindNumber) && n.number <= 0 {
+		d.issue(n.offset, path, "expected a positive amount, got %g", n.number)
+	}
+}
+
+func currency(d *Document, path string, n *node) {
+	if d.expect(path, n, kindString) && !currencyCode.MatchString(n.text) {
+		d.issue(n.offset, path, "invalid currency %q, expected a code such as EUR", n.text)
+	}
+}
+
+func compounding(d *Document, path string, n *node) {
+	if !d.expect(path, n, kindString) {
+		return
+	}
+	if _, ok := compoundingPeriods[n.text]; !ok && n.text != CompoundingNone {
+		d.issue(n.offset, path, "unknown compounding %q, expected none, daily, monthly, quarterly or annually", n.text)
+	}
+}
+
+// date accepts an empty string as no date, as Date does
+func date(d *Document, path string, n *node) {
+	if !d.expect(path, n, kindString) || n.text == "" {
+		return
+	}
+	if _, err := time.Parse(DateLayout, n.text); err != nil {
+		d.issue(n.offset, path, "invalid date %q, expected %s", n.text, DateLayout)
+	}
+}
+
+func pastDate(d *Document, path string, n *node) {
+	if !d.expect(path, n, kindString) || n.text == "" {
+		return
+	}
+	day, err := time.Parse(DateLayout, n.text)
+	if err != nil {
+		d.issue(n.offset, path, "invalid date %q, expected %s", n.text, DateLayout)
+		return
+	}
+	if day.After(time.Now()) {
+		d.issue(n.offset, path, "date %s is in the future", n.text)
+	}
+}
+
+func fieldNames(fields map[string]fieldCheck) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedMetals() []string {
+	metals := make([]string, 0, len(bullion.Metals))
+	for metal := range bullion.Metals {
+		metals = append(metals, metal)
+	}
+	sort.Strings(metals)
+	return metals
+}
@@ -0,0 +1,180 @@
+package pricecache
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTTL applies to sources without a TTL of their own
+const DefaultTTL = 15 * time.Minute
+
+// defaultTTLs reflect how often each source actually moves or how scarce its credits are
+var defaultTTLs = map[string]time.Duration{
+	"coinmarketcap": 15 * time.Minute,
+	"coingecko":     15 * time.Minute,
+	"binance":       5 * time.Minute,
+	"goldapi":       time.Hour,
+	"trading212":    5 * time.Minute,
+
+	// Pie names change rarely and cost a rate-limited request each
+	"trading212-pie-names": 24 * time.Hour,
+}
+
+// Entry is a cached price
+type Entry struct {
+	Price     float64   `json:"price"`
+	Currency  string    `json:"currency"`
+	Timestamp time.Time `json:"timestamp"`
+	FetchedAt time.Time `json:"fetched_at"`
+
+	// Data holds structured detail fetched with the price, such as a pie breakdown
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Cache is a JSON file of prices keyed by source, symbol and currency
+type Cache struct {
+	path    string
+	ttls    map[string]time.Duration
+	refresh bool
+
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+// Open loads the cache file at path; a missing or unreadable file starts an empty cache
+func Open(path string) *Cache {
+	c := &Cache{
+		path:    path,
+		ttls:    make(map[string]time.Duration),
+		entries: make(map[string]Entry),
+	}
+	for source, ttl := range defaultTTLs {
+		c.ttls[source] = ttl
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: Could not read price cache %s: %v", path, err)
+		}
+		return c
+	}
+
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		log.Printf("Warning: Ignoring corrupt price cache %s: %v", path, err)
+		c.entries = make(map[string]Entry)
+	}
+
+	return c
+}
+
+// OpenFromEnv opens the cache at PRICE_CACHE_PATH, or in the user cache directory,
+// applying TTL overrides from PRICE_CACHE_TTLS ("goldapi=2h,coinmarketcap=30m")
+func OpenFromEnv() (*Cache, error) {
+	path := os.Getenv("PRICE_CACHE_PATH")
+	if path == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			dir = os.TempDir()
+		}
+		path = filepath.Join(dir, "investment-tracker", "prices.json")
+	}
+
+	c := Open(path)
+
+	if ttls := os.Getenv("PRICE_CACHE_TTLS"); ttls != "" {
+		for _, pair := range strings.Split(ttls, ",") {
+			source, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid PRICE_CACHE_TTLS entry %q, expected source=duration", pair)
+			}
+			ttl, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TTL for %s: %w", source, err)
+			}
+			c.SetTTL(strings.ToLower(source), ttl)
+		}
+	}
+
+	return c, nil
+}
+
+// SetTTL sets how long prices from a source stay fresh
+func (c *Cache) SetTTL(source string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttls[source] = ttl
+}
+
+// ForceRefresh makes every lookup miss while still storing fresh prices
+func (c *Cache) ForceRefresh() {
+	c.refresh = true
+}
+
+// Get returns a price that is still within its source's TTL
+func (c *Cache) Get(source, symbol, currency string) (Entry, bool) {
+	if c.refresh {
+		return Entry{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key(source, symbol, currency)]
+	if !ok {
+		return Entry{}, false
+	}
+
+	ttl, ok := c.ttls[source]
+	if !ok {
+		ttl = DefaultTTL
+	}
+	if time.Since(entry.FetchedAt) > ttl {
+		return Entry{}, false
+	}
+
+	return entry, true
+}
+
+// Put stores a freshly fetched price and writes the cache file
+func (c *Cache) Put(source, symbol, currency string, entry Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry.FetchedAt.IsZero() {
+		entry.FetchedAt = time.Now()
+	}
+	c.entries[key(source, symbol, currency)] = entry
+
+	return c.save()
+}
+
+// save writes the cache to a temporary file and renames it into place,
+// so a crashed run never leaves a half-written cache behind
+func (c *Cache) save() error {
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal price cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create price cache directory: %w", err)
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write price cache: %w", err)
+	}
+
+	return os.Rename(tmp, c.path)
+}
+
+func key(source, symbol, currency string) string {
+	return source + "|" + symbol + "|" + currency
+}
@@ -2,9 +2,11 @@ package stocks
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"time"
 )
@@ -18,6 +20,7 @@ type Client struct {
 	apiKey  string
 	baseURL string
 	client  *http.Client
+	limiter *rateLimiter
 }
 
 func NewClient(apiKey string, isLive bool) *Client {
@@ -32,21 +35,74 @@ func NewClient(apiKey string, isLive bool) *Client {
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		limiter: newRateLimiter(),
 	}
 }
 
-func (c *Client) makeRequest(method, endpoint string, body interface{}) ([]byte, error) {
-	var reqBody io.Reader
+func (c *Client) makeRequest(ctx context.Context, method, endpoint string, body interface{}) ([]byte, error) {
+	var jsonData []byte
 	if body != nil {
-		jsonData, err := json.Marshal(body)
+		var err error
+		jsonData, err = json.Marshal(body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
-		reqBody = bytes.NewBuffer(jsonData)
+	}
+
+	limit := c.limiter.bucket(endpointKey(method, endpoint))
+
+	for attempt := 0; ; attempt++ {
+		if err := limit.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		responseBody, err := c.doRequest(ctx, method, endpoint, jsonData, limit)
+		if err == nil {
+			return responseBody, nil
+		}
+
+		apiErr, ok := err.(*APIError)
+		if !ok || !c.retryable(method, apiErr) || attempt == maxRetries {
+			return nil, err
+		}
+
+		delay := backoff(attempt)
+		if apiErr.RetryAfter > delay {
+			delay = apiErr.RetryAfter
+		}
+		if apiErr.StatusCode == http.StatusTooManyRequests {
+			limit.block(delay)
+		}
+		log.Printf("Trading212 %s %s failed with HTTP %d, retrying in %s", method, endpoint, apiErr.StatusCode, delay.Round(time.Millisecond))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		}
+	}
+}
+
+// retryable reports whether a failed request is safe to repeat. A throttled
+// request was never executed, but a POST that hit a server error may have been,
+// so orders are not resent
+func (c *Client) retryable(method string, err *APIError) bool {
+	if err.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return err.StatusCode >= 500 && method != http.MethodPost
+}
+
+func (c *Client) doRequest(ctx context.Context, method, endpoint string, jsonData []byte, limit *bucket) ([]byte, error) {
+	var reqBody io.Reader
+	if jsonData != nil {
+		reqBody = bytes.NewReader(jsonData)
 	}
 
 	url := c.baseURL + endpoint
-	req, err := http.NewRequest(method, url, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -60,13 +116,15 @@ func (c *Client) makeRequest(method, endpoint string, body interface{}) ([]byte,
 	}
 	defer resp.Body.Close()
 
+	limit.update(resp.Header)
+
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(responseBody))
+		return nil, newAPIError(method, endpoint, resp, responseBody)
 	}
 
 	return responseBody, nil
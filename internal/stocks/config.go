@@ -3,6 +3,7 @@ package stocks
 import (
 	"fmt"
 	"os"
+	"strings"
 )
 
 type Config struct {
@@ -31,4 +32,104 @@ func NewClientFromConfig() (*Client, error) {
 	}
 
 	return NewClient(config.APIKey, config.IsLive), nil
-}
\ No newline at end of file
+}
+
+// DefaultAccount names the single account configured by TRADING212_API_KEY
+const DefaultAccount = "Trading212"
+
+// Account is one named Trading212 account with its own key and environment
+type Account struct {
+	Name   string
+	APIKey string
+	IsLive bool
+}
+
+// LoadAccounts returns the accounts listed in TRADING212_ACCOUNTS, each configured
+// by TRADING212_<NAME>_API_KEY and TRADING212_<NAME>_IS_LIVE. Without the list the
+// TRADING212_API_KEY account is returned as DefaultAccount
+func LoadAccounts() ([]Account, error) {
+	names := os.Getenv("TRADING212_ACCOUNTS")
+	if strings.TrimSpace(names) == "" {
+		config, err := LoadConfig()
+		if err != nil {
+			return nil, err
+		}
+		return []Account{{Name: DefaultAccount, APIKey: config.APIKey, IsLive: config.IsLive}}, nil
+	}
+
+	var accounts []Account
+	seen := map[string]bool{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		prefix := "TRADING212_" + envName(name)
+		if seen[prefix] {
+			return nil, fmt.Errorf("Trading212 account %s is listed twice", name)
+		}
+		seen[prefix] = true
+
+		apiKey := os.Getenv(prefix + "_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("%s_API_KEY environment variable is required for Trading212 account %s", prefix, name)
+		}
+
+		accounts = append(accounts, Account{
+			Name:   name,
+			APIKey: apiKey,
+			IsLive: os.Getenv(prefix+"_IS_LIVE") == "true",
+		})
+	}
+
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("no Trading212 accounts configured")
+	}
+
+	return accounts, nil
+}
+
+// FindAccount returns a configured account by name; an empty name selects the only account
+func FindAccount(name string) (*Account, error) {
+	accounts, err := LoadAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		if len(accounts) > 1 {
+			return nil, fmt.Errorf("several Trading212 accounts are configured, choose one of: %s", accountNames(accounts))
+		}
+		return &accounts[0], nil
+	}
+
+	for i := range accounts {
+		if strings.EqualFold(accounts[i].Name, name) {
+			return &accounts[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown Trading212 account %s, expected one of: %s", name, accountNames(accounts))
+}
+
+// envName turns an account name into its environment variable infix, e.g. "my isa" into MY_ISA
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func accountNames(accounts []Account) string {
+	names := make([]string, len(accounts))
+	for i, account := range accounts {
+		names[i] = account.Name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,94 @@
+package stocks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sentinel errors matched by APIError, so callers can use errors.Is through wrapped errors
+var (
+	ErrUnauthorized = errors.New("trading212: unauthorized")
+	ErrForbidden    = errors.New("trading212: forbidden")
+	ErrNotFound     = errors.New("trading212: not found")
+	ErrRateLimited  = errors.New("trading212: rate limited")
+	ErrServer       = errors.New("trading212: server error")
+)
+
+// ErrorBody is the JSON error payload returned by the Trading212 API
+type ErrorBody struct {
+	Code          string `json:"code"`
+	Clarification string `json:"clarification"`
+	Message       string `json:"message"`
+}
+
+// APIError is a non-success response from the Trading212 API
+type APIError struct {
+	Method     string
+	Endpoint   string
+	StatusCode int
+	Body       ErrorBody
+	RawBody    string
+
+	// RetryAfter is how long the API asked us to wait, when it said so
+	RetryAfter time.Duration
+}
+
+func newAPIError(method, endpoint string, resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		Method:     method,
+		Endpoint:   endpoint,
+		StatusCode: resp.StatusCode,
+		RawBody:    string(body),
+		RetryAfter: retryAfter(resp.Header),
+	}
+
+	// Not every error response is JSON; the raw body is kept either way
+	_ = json.Unmarshal(body, &apiErr.Body)
+
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	detail := e.Body.Clarification
+	if detail == "" {
+		detail = e.Body.Message
+	}
+	if detail == "" {
+		detail = e.RawBody
+	}
+	if e.Body.Code != "" {
+		detail = e.Body.Code + ": " + detail
+	}
+
+	return fmt.Sprintf("API error %d on %s %s: %s", e.StatusCode, e.Method, e.Endpoint, detail)
+}
+
+// Is matches the sentinel error for the status code
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// IsAuth reports whether the API key is missing, wrong or lacks a permission
+func (e *APIError) IsAuth() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// Temporary reports whether the request may succeed if retried later
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
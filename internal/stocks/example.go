@@ -1,29 +1,32 @@
 package stocks
 
 import (
+	"context"
 	"fmt"
 	"log"
 )
 
 func ExampleUsage() {
+	ctx := context.Background()
+
 	client, err := NewClientFromConfig()
 	if err != nil {
 		log.Fatal("Failed to create client:", err)
 	}
 
-	accountInfo, err := client.GetAccountInfo()
+	accountInfo, err := client.GetAccountInfo(ctx)
 	if err != nil {
 		log.Fatal("Failed to get account info:", err)
 	}
 	fmt.Printf("Account ID: %d, Currency: %s\n", accountInfo.ID, accountInfo.CurrencyCode)
 
-	cash, err := client.GetAccountCash()
+	cash, err := client.GetAccountCash(ctx)
 	if err != nil {
 		log.Fatal("Failed to get account cash:", err)
 	}
 	fmt.Printf("Available Cash: %.2f %s\n", cash.Free, cash.CurrencyCode)
 
-	portfolio, err := client.GetPortfolio()
+	portfolio, err := client.GetPortfolio(ctx)
 	if err != nil {
 		log.Fatal("Failed to get portfolio:", err)
 	}
@@ -36,7 +39,7 @@ func ExampleUsage() {
 			position.Ticker, position.Quantity, position.CurrentPrice, currentValue, pnl)
 	}
 
-	orders, err := client.GetOrders()
+	orders, err := client.GetOrders(ctx)
 	if err != nil {
 		log.Fatal("Failed to get orders:", err)
 	}
@@ -47,20 +50,20 @@ func ExampleUsage() {
 			order.ID, order.Ticker, order.Type, order.Status, order.Quantity)
 	}
 
-	dividends, err := client.GetDividends("", 10)
+	dividends, err := client.GetDividends(ctx, "", HistoryQuery{Limit: 10})
 	if err != nil {
 		log.Fatal("Failed to get dividends:", err)
 	}
 
 	fmt.Printf("\nRecent Dividends:\n")
-	for _, dividend := range dividends {
+	for _, dividend := range dividends.Items {
 		fmt.Printf("Ticker: %s, Amount: %.2f, Date: %s\n",
 			dividend.Ticker, dividend.CashAmount, dividend.PaidOn.Format("2006-01-02"))
 	}
 }
 
-func CalculatePortfolioValue(client *Client) (float64, error) {
-	portfolio, err := client.GetPortfolio()
+func CalculatePortfolioValue(ctx context.Context, client *Client) (float64, error) {
+	portfolio, err := client.GetPortfolio(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -73,13 +76,13 @@ func CalculatePortfolioValue(client *Client) (float64, error) {
 	return totalValue, nil
 }
 
-func GetPortfolioSummary(client *Client) (*PortfolioSummary, error) {
-	portfolio, err := client.GetPortfolio()
+func GetPortfolioSummary(ctx context.Context, client *Client) (*PortfolioSummary, error) {
+	portfolio, err := client.GetPortfolio(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	cash, err := client.GetAccountCash()
+	cash, err := client.GetAccountCash(ctx)
 	if err != nil {
 		return nil, err
 	}
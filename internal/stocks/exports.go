@@ -0,0 +1,303 @@
+package stocks
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export report statuses
+const (
+	ExportQueued     = "Queued"
+	ExportProcessing = "Processing"
+	ExportRunning    = "Running"
+	ExportCanceled   = "Canceled"
+	ExportFailed     = "Failed"
+	ExportFinished   = "Finished"
+)
+
+// exportPollInterval is how often a pending export is checked; the list
+// endpoint is limited to one request a minute, which the limiter enforces
+const exportPollInterval = 15 * time.Second
+
+// ExportData selects what an export report contains
+type ExportData struct {
+	IncludeDividends    bool `json:"includeDividends"`
+	IncludeInterest     bool `json:"includeInterest"`
+	IncludeOrders       bool `json:"includeOrders"`
+	IncludeTransactions bool `json:"includeTransactions"`
+}
+
+// ExportAll includes every kind of activity in an export
+var ExportAll = ExportData{
+	IncludeDividends:    true,
+	IncludeInterest:     true,
+	IncludeOrders:       true,
+	IncludeTransactions: true,
+}
+
+type ExportRequest struct {
+	DataIncluded ExportData `json:"dataIncluded"`
+	TimeFrom     time.Time  `json:"timeFrom"`
+	TimeTo       time.Time  `json:"timeTo"`
+}
+
+// ExportReport is the state of a requested export
+type ExportReport struct {
+	ReportID     int64      `json:"reportId"`
+	TimeFrom     time.Time  `json:"timeFrom"`
+	TimeTo       time.Time  `json:"timeTo"`
+	DataIncluded ExportData `json:"dataIncluded"`
+	Status       string     `json:"status"`
+	DownloadLink string     `json:"downloadLink"`
+}
+
+// ExportRow is one line of an export CSV. Columns missing from a report are left zero
+type ExportRow struct {
+	Action                 string
+	Time                   time.Time
+	ISIN                   string
+	Ticker                 string
+	Name                   string
+	Shares                 float64
+	PricePerShare          float64
+	PriceCurrency          string
+	ExchangeRate           float64
+	Result                 float64
+	ResultCurrency         string
+	Total                  float64
+	TotalCurrency          string
+	WithholdingTax         float64
+	WithholdingTaxCurrency string
+	ConversionFee          float64
+	ConversionFeeCurrency  string
+	Notes                  string
+	ID                     string
+}
+
+func (c *Client) GetExports(ctx context.Context) ([]ExportReport, error) {
+	data, err := c.makeRequest(ctx, http.MethodGet, "/history/exports", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exports: %w", err)
+	}
+
+	var reports []ExportReport
+	if err := json.Unmarshal(data, &reports); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal exports: %w", err)
+	}
+
+	return reports, nil
+}
+
+// RequestExport asks Trading212 to prepare a CSV report and returns its id
+func (c *Client) RequestExport(ctx context.Context, request ExportRequest) (int64, error) {
+	if !request.TimeFrom.Before(request.TimeTo) {
+		return 0, fmt.Errorf("export start must be before its end")
+	}
+
+	data, err := c.makeRequest(ctx, http.MethodPost, "/history/exports", request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to request export: %w", err)
+	}
+
+	var response struct {
+		ReportID int64 `json:"reportId"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal export response: %w", err)
+	}
+
+	return response.ReportID, nil
+}
+
+// WaitForExport polls until a report is finished and returns it
+func (c *Client) WaitForExport(ctx context.Context, reportID int64) (*ExportReport, error) {
+	for {
+		reports, err := c.GetExports(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var report *ExportReport
+		for i := range reports {
+			if reports[i].ReportID == reportID {
+				report = &reports[i]
+				break
+			}
+		}
+
+		switch {
+		case report == nil:
+			log.Printf("Export %d is not listed yet", reportID)
+		case report.Status == ExportFinished:
+			return report, nil
+		case report.Status == ExportFailed || report.Status == ExportCanceled:
+			return nil, fmt.Errorf("export %d %s", reportID, strings.ToLower(report.Status))
+		default:
+			log.Printf("Export %d is %s", reportID, strings.ToLower(report.Status))
+		}
+
+		timer := time.NewTimer(exportPollInterval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("gave up waiting for export %d: %w", reportID, ctx.Err())
+		}
+	}
+}
+
+// DownloadExport downloads a finished report's CSV
+func (c *Client) DownloadExport(ctx context.Context, report *ExportReport) ([]byte, error) {
+	if report.DownloadLink == "" {
+		return nil, fmt.Errorf("export %d has no download link", report.ReportID)
+	}
+
+	// The link is pre-signed, so it must not carry the API key
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, report.DownloadLink, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download export %d: %w", report.ReportID, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export %d: %w", report.ReportID, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download export %d: HTTP %d", report.ReportID, resp.StatusCode)
+	}
+
+	return data, nil
+}
+
+// Export runs the whole export flow for a time range: request the report,
+// wait for it, download it and return the raw CSV
+func (c *Client) Export(ctx context.Context, request ExportRequest) ([]byte, error) {
+	reportID, err := c.RequestExport(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := c.WaitForExport(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.DownloadExport(ctx, report)
+}
+
+// ParseExport parses an export CSV into typed rows, matching columns by header
+func ParseExport(r io.Reader) ([]ExportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read export header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+
+	var rows []ExportRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read export line %d: %w", line, err)
+		}
+
+		row, err := parseExportRow(columns, record)
+		if err != nil {
+			return nil, fmt.Errorf("invalid export line %d: %w", line, err)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseExportRow(columns map[string]int, record []string) (ExportRow, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var parseErr error
+	number := func(name string) float64 {
+		// Some columns, such as the exchange rate, say "Not available" instead of being empty
+		value := field(name)
+		if value == "" || value == "Not available" || parseErr != nil {
+			return 0
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			parseErr = fmt.Errorf("column %q: %w", name, err)
+		}
+		return n
+	}
+
+	row := ExportRow{
+		Action:                 field("Action"),
+		ISIN:                   field("ISIN"),
+		Ticker:                 field("Ticker"),
+		Name:                   field("Name"),
+		Shares:                 number("No. of shares"),
+		PricePerShare:          number("Price / share"),
+		PriceCurrency:          field("Currency (Price / share)"),
+		ExchangeRate:           number("Exchange rate"),
+		Result:                 number("Result"),
+		ResultCurrency:         field("Currency (Result)"),
+		Total:                  number("Total"),
+		TotalCurrency:          field("Currency (Total)"),
+		WithholdingTax:         number("Withholding tax"),
+		WithholdingTaxCurrency: field("Currency (Withholding tax)"),
+		ConversionFee:          number("Currency conversion fee"),
+		ConversionFeeCurrency:  field("Currency (Currency conversion fee)"),
+		Notes:                  field("Notes"),
+		ID:                     field("ID"),
+	}
+	if parseErr != nil {
+		return row, parseErr
+	}
+
+	var err error
+	row.Time, err = parseExportTime(field("Time"))
+	if err != nil {
+		return row, err
+	}
+
+	return row, nil
+}
+
+func parseExportTime(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05.000", "2006-01-02 15:04:05", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
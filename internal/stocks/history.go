@@ -0,0 +1,229 @@
+package stocks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxPageSize is the largest page the history endpoints return
+const MaxPageSize = 50
+
+// Page is one page of a paginated history endpoint. NextPagePath is empty on the last page
+type Page[T any] struct {
+	Items        []T    `json:"items"`
+	NextPagePath string `json:"nextPagePath"`
+}
+
+// HistoryQuery filters a history endpoint
+type HistoryQuery struct {
+	// Ticker restricts orders and dividends to one instrument
+	Ticker string
+
+	// Limit is the page size, up to MaxPageSize
+	Limit int
+
+	// Since stops iterators at the first item older than this; history is newest first
+	Since time.Time
+}
+
+// HistoricalOrder is an order from the order history, filled or not
+type HistoricalOrder struct {
+	ID              int64     `json:"id"`
+	ParentOrder     int64     `json:"parentOrder"`
+	Ticker          string    `json:"ticker"`
+	Type            string    `json:"type"`
+	Status          string    `json:"status"`
+	Executor        string    `json:"executor"`
+	TimeValidity    string    `json:"timeValidity"`
+	OrderedQuantity float64   `json:"orderedQuantity"`
+	OrderedValue    float64   `json:"orderedValue"`
+	FilledQuantity  float64   `json:"filledQuantity"`
+	FilledValue     float64   `json:"filledValue"`
+	LimitPrice      float64   `json:"limitPrice"`
+	StopPrice       float64   `json:"stopPrice"`
+	FillPrice       float64   `json:"fillPrice"`
+	FillCost        float64   `json:"fillCost"`
+	FillID          int64     `json:"fillId"`
+	FillType        string    `json:"fillType"`
+	FillResult      float64   `json:"fillResult"`
+	Taxes           []Tax     `json:"taxes"`
+	DateCreated     time.Time `json:"dateCreated"`
+	DateExecuted    time.Time `json:"dateExecuted"`
+	DateModified    time.Time `json:"dateModified"`
+}
+
+// Tax is a fee or tax charged on an order fill
+type Tax struct {
+	Name        string    `json:"name"`
+	Quantity    float64   `json:"quantity"`
+	FillID      string    `json:"fillId"`
+	TimeCharged time.Time `json:"timeCharged"`
+}
+
+// GetHistoricalOrders returns one page of the order history
+func (c *Client) GetHistoricalOrders(ctx context.Context, cursor string, query HistoryQuery) (*Page[HistoricalOrder], error) {
+	var page Page[HistoricalOrder]
+	if err := c.getPage(ctx, historyPath("/equity/history/orders", cursor, query, true), &page); err != nil {
+		return nil, fmt.Errorf("failed to get historical orders: %w", err)
+	}
+	return &page, nil
+}
+
+// GetDividends returns one page of paid dividends
+func (c *Client) GetDividends(ctx context.Context, cursor string, query HistoryQuery) (*Page[Dividend], error) {
+	var page Page[Dividend]
+	if err := c.getPage(ctx, historyPath("/history/dividends", cursor, query, true), &page); err != nil {
+		return nil, fmt.Errorf("failed to get dividends: %w", err)
+	}
+	return &page, nil
+}
+
+// GetTransactions returns one page of deposits, withdrawals, fees and transfers
+func (c *Client) GetTransactions(ctx context.Context, cursor string, query HistoryQuery) (*Page[Transaction], error) {
+	var page Page[Transaction]
+	if err := c.getPage(ctx, historyPath("/history/transactions", cursor, query, false), &page); err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+	return &page, nil
+}
+
+// HistoricalOrders iterates over the whole order history, newest first
+func (c *Client) HistoricalOrders(query HistoryQuery) *Iterator[HistoricalOrder] {
+	return newIterator(c, historyPath("/equity/history/orders", "", query, true), query.Since,
+		func(order HistoricalOrder) time.Time { return order.DateCreated })
+}
+
+// Dividends iterates over every paid dividend, newest first
+func (c *Client) Dividends(query HistoryQuery) *Iterator[Dividend] {
+	return newIterator(c, historyPath("/history/dividends", "", query, true), query.Since,
+		func(dividend Dividend) time.Time { return dividend.PaidOn })
+}
+
+// Transactions iterates over every account transaction, newest first
+func (c *Client) Transactions(query HistoryQuery) *Iterator[Transaction] {
+	return newIterator(c, historyPath("/history/transactions", "", query, false), query.Since,
+		func(transaction Transaction) time.Time { return transaction.DateTime })
+}
+
+// Iterator walks a paginated history endpoint, fetching pages as they are
+// needed through the client's rate limiter
+type Iterator[T any] struct {
+	client *Client
+	next   string
+	since  time.Time
+	timeOf func(T) time.Time
+
+	page []T
+	item T
+	err  error
+	done bool
+}
+
+func newIterator[T any](client *Client, first string, since time.Time, timeOf func(T) time.Time) *Iterator[T] {
+	return &Iterator[T]{
+		client: client,
+		next:   first,
+		since:  since,
+		timeOf: timeOf,
+	}
+}
+
+// Next advances to the next item, fetching the next page when needed. It
+// returns false at the end of the history, at Since, or on an error
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil || it.next == "" {
+			return false
+		}
+
+		var page Page[T]
+		if err := it.client.getPage(ctx, it.next, &page); err != nil {
+			it.err = err
+			return false
+		}
+		it.page = page.Items
+		it.next = it.client.relativePath(page.NextPagePath)
+	}
+
+	it.item, it.page = it.page[0], it.page[1:]
+	if !it.since.IsZero() && it.timeOf(it.item).Before(it.since) {
+		it.done = true
+		it.page = nil
+		return false
+	}
+
+	return true
+}
+
+// Item returns the current item
+func (it *Iterator[T]) Item() T {
+	return it.item
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// Collect drains an iterator into a slice
+func Collect[T any](ctx context.Context, it *Iterator[T]) ([]T, error) {
+	var items []T
+	for it.Next(ctx) {
+		items = append(items, it.Item())
+	}
+	return items, it.Err()
+}
+
+func (c *Client) getPage(ctx context.Context, endpoint string, page interface{}) error {
+	data, err := c.makeRequest(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, page); err != nil {
+		return fmt.Errorf("failed to unmarshal page: %w", err)
+	}
+
+	return nil
+}
+
+// relativePath turns a nextPagePath, which includes the API prefix, into an
+// endpoint relative to the base URL
+func (c *Client) relativePath(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	if base, err := url.Parse(c.baseURL); err == nil && base.Path != "" {
+		path = strings.TrimPrefix(path, base.Path)
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	return path
+}
+
+func historyPath(endpoint, cursor string, query HistoryQuery, byTicker bool) string {
+	values := url.Values{}
+	if cursor != "" {
+		values.Set("cursor", cursor)
+	}
+	if byTicker && query.Ticker != "" {
+		values.Set("ticker", query.Ticker)
+	}
+
+	limit := query.Limit
+	if limit <= 0 || limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	values.Set("limit", strconv.Itoa(limit))
+
+	return endpoint + "?" + values.Encode()
+}
@@ -0,0 +1,175 @@
+package stocks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+)
+
+// What happens to dividends paid out by the instruments in a pie
+const (
+	DividendReinvest      = "REINVEST"
+	DividendToAccountCash = "TO_ACCOUNT_CASH"
+)
+
+// Pie is the summary of a pie as returned by the pie list
+type Pie struct {
+	ID              int64           `json:"id"`
+	Cash            float64         `json:"cash"`
+	DividendDetails DividendDetails `json:"dividendDetails"`
+	Result          PieResult       `json:"result"`
+	Progress        float64         `json:"progress"`
+	Status          string          `json:"status"`
+}
+
+type DividendDetails struct {
+	Gained     float64 `json:"gained"`
+	Reinvested float64 `json:"reinvested"`
+	InCash     float64 `json:"inCash"`
+}
+
+// PieResult is the invested amount, current value and return of a pie or pie instrument
+type PieResult struct {
+	PriceAvgInvestedValue float64 `json:"priceAvgInvestedValue"`
+	PriceAvgValue         float64 `json:"priceAvgValue"`
+	PriceAvgResult        float64 `json:"priceAvgResult"`
+	PriceAvgResultCoef    float64 `json:"priceAvgResultCoef"`
+}
+
+// PieDetails is a pie's instruments and settings
+type PieDetails struct {
+	Instruments []PieInstrument `json:"instruments"`
+	Settings    PieSettings     `json:"settings"`
+}
+
+type PieInstrument struct {
+	Ticker        string     `json:"ticker"`
+	CurrentShare  float64    `json:"currentShare"`
+	ExpectedShare float64    `json:"expectedShare"`
+	OwnedQuantity float64    `json:"ownedQuantity"`
+	Result        PieResult  `json:"result"`
+	Issues        []PieIssue `json:"issues"`
+}
+
+type PieIssue struct {
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+}
+
+type PieSettings struct {
+	ID                 int64              `json:"id"`
+	Name               string             `json:"name"`
+	Icon               string             `json:"icon"`
+	Goal               float64            `json:"goal"`
+	EndDate            *time.Time         `json:"endDate"`
+	InitialInvestment  float64            `json:"initialInvestment"`
+	DividendCashAction string             `json:"dividendCashAction"`
+	InstrumentShares   map[string]float64 `json:"instrumentShares"`
+	PublicURL          string             `json:"publicUrl"`
+}
+
+// PieRequest creates or updates a pie. InstrumentShares maps tickers to target
+// weights, which must add up to 1
+type PieRequest struct {
+	Name               string             `json:"name"`
+	Icon               string             `json:"icon,omitempty"`
+	Goal               float64            `json:"goal,omitempty"`
+	EndDate            *time.Time         `json:"endDate,omitempty"`
+	DividendCashAction string             `json:"dividendCashAction"`
+	InstrumentShares   map[string]float64 `json:"instrumentShares"`
+}
+
+// Validate checks a pie request before it is sent
+func (r PieRequest) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("pie name is required")
+	}
+	if r.DividendCashAction != DividendReinvest && r.DividendCashAction != DividendToAccountCash {
+		return fmt.Errorf("dividend cash action must be %s or %s", DividendReinvest, DividendToAccountCash)
+	}
+	if len(r.InstrumentShares) == 0 {
+		return fmt.Errorf("a pie needs at least one instrument")
+	}
+
+	total := 0.0
+	for ticker, share := range r.InstrumentShares {
+		if share <= 0 {
+			return fmt.Errorf("share of %s must be positive", ticker)
+		}
+		total += share
+	}
+	if math.Abs(total-1) > 0.0001 {
+		return fmt.Errorf("instrument shares add up to %.4f, expected 1", total)
+	}
+
+	return nil
+}
+
+func (c *Client) GetPies(ctx context.Context) ([]Pie, error) {
+	data, err := c.makeRequest(ctx, http.MethodGet, "/equity/pies", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pies: %w", err)
+	}
+
+	var pies []Pie
+	if err := json.Unmarshal(data, &pies); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pies: %w", err)
+	}
+
+	return pies, nil
+}
+
+func (c *Client) GetPie(ctx context.Context, pieID int64) (*PieDetails, error) {
+	data, err := c.makeRequest(ctx, http.MethodGet, fmt.Sprintf("/equity/pies/%d", pieID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pie %d: %w", pieID, err)
+	}
+
+	return unmarshalPie(data)
+}
+
+func (c *Client) CreatePie(ctx context.Context, request PieRequest) (*PieDetails, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	data, err := c.makeRequest(ctx, http.MethodPost, "/equity/pies", request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pie: %w", err)
+	}
+
+	return unmarshalPie(data)
+}
+
+func (c *Client) UpdatePie(ctx context.Context, pieID int64, request PieRequest) (*PieDetails, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	data, err := c.makeRequest(ctx, http.MethodPost, fmt.Sprintf("/equity/pies/%d", pieID), request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update pie %d: %w", pieID, err)
+	}
+
+	return unmarshalPie(data)
+}
+
+func (c *Client) DeletePie(ctx context.Context, pieID int64) error {
+	_, err := c.makeRequest(ctx, http.MethodDelete, fmt.Sprintf("/equity/pies/%d", pieID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete pie %d: %w", pieID, err)
+	}
+
+	return nil
+}
+
+func unmarshalPie(data []byte) (*PieDetails, error) {
+	var pie PieDetails
+	if err := json.Unmarshal(data, &pie); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pie: %w", err)
+	}
+	return &pie, nil
+}
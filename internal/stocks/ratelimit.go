@@ -0,0 +1,217 @@
+package stocks
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRetries is how many times a throttled or failed request is retried
+	maxRetries = 4
+
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 30 * time.Second
+)
+
+// endpointLimit is a documented Trading212 rate limit
+type endpointLimit struct {
+	requests int
+	period   time.Duration
+}
+
+// documentedLimits seed the limiter before the API has sent any x-ratelimit-* headers
+var documentedLimits = map[string]endpointLimit{
+	"GET /equity/account/cash":         {1, 2 * time.Second},
+	"GET /equity/account/info":         {1, 30 * time.Second},
+	"GET /equity/portfolio":            {1, 5 * time.Second},
+	"GET /equity/portfolio/{id}":       {1, time.Second},
+	"GET /equity/orders":               {1, 5 * time.Second},
+	"GET /equity/orders/{id}":          {1, time.Second},
+	"POST /equity/orders/market":       {50, time.Minute},
+	"POST /equity/orders/limit":        {1, 2 * time.Second},
+	"POST /equity/orders/stop":         {1, 2 * time.Second},
+	"POST /equity/orders/stop_limit":   {1, 2 * time.Second},
+	"DELETE /equity/orders/{id}":       {50, time.Minute},
+	"GET /equity/pies":                 {1, 30 * time.Second},
+	"GET /equity/pies/{id}":            {1, 5 * time.Second},
+	"POST /equity/pies":                {1, 5 * time.Second},
+	"POST /equity/pies/{id}":           {1, 5 * time.Second},
+	"DELETE /equity/pies/{id}":         {1, 5 * time.Second},
+	"GET /equity/metadata/exchanges":   {1, 30 * time.Second},
+	"GET /equity/metadata/instruments": {1, 50 * time.Second},
+	"GET /equity/history/orders":       {6, time.Minute},
+	"GET /history/dividends":           {6, time.Minute},
+	"GET /history/transactions":        {6, time.Minute},
+	"GET /history/exports":             {1, time.Minute},
+	"POST /history/exports":            {1, 30 * time.Second},
+}
+
+// parameterised lists the endpoints whose last path segment is an id or ticker
+var parameterised = []string{
+	"GET /equity/portfolio/",
+	"GET /equity/orders/",
+	"DELETE /equity/orders/",
+	"GET /equity/pies/",
+	"POST /equity/pies/",
+	"DELETE /equity/pies/",
+}
+
+// endpointKey maps a request to the endpoint its rate limit applies to
+func endpointKey(method, endpoint string) string {
+	path, _, _ := strings.Cut(endpoint, "?")
+	key := method + " " + path
+
+	for _, prefix := range parameterised {
+		if strings.HasPrefix(key, prefix) && len(key) > len(prefix) && !strings.Contains(key[len(prefix):], "/") {
+			return prefix + "{id}"
+		}
+	}
+
+	return key
+}
+
+// rateLimiter keeps a token bucket per endpoint
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (l *rateLimiter) bucket(key string) *bucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{last: time.Now()}
+		if limit, ok := documentedLimits[key]; ok {
+			b.setLimit(limit.requests, limit.period)
+			b.tokens = b.capacity
+		}
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// bucket is a token bucket; an unconfigured bucket lets requests through
+// until the API reports its limit
+type bucket struct {
+	mu           sync.Mutex
+	capacity     float64
+	tokens       float64
+	refill       float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+func (b *bucket) setLimit(requests int, period time.Duration) {
+	b.capacity = float64(requests)
+	b.refill = float64(requests) / period.Seconds()
+}
+
+// wait blocks until the bucket has a token or ctx is done
+func (b *bucket) wait(ctx context.Context) error {
+	for {
+		delay := b.take()
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// take consumes a token, or returns how long until one is available
+func (b *bucket) take() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(b.blockedUntil) {
+		return b.blockedUntil.Sub(now)
+	}
+	if b.refill == 0 {
+		return 0
+	}
+
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.refill)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.refill * float64(time.Second))
+}
+
+// update adopts the limit the API reported in its x-ratelimit-* headers
+func (b *bucket) update(header http.Header) {
+	limit, errLimit := strconv.Atoi(header.Get("x-ratelimit-limit"))
+	period, errPeriod := strconv.Atoi(header.Get("x-ratelimit-period"))
+	remaining, errRemaining := strconv.Atoi(header.Get("x-ratelimit-remaining"))
+	reset, errReset := strconv.ParseInt(header.Get("x-ratelimit-reset"), 10, 64)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if errLimit == nil && errPeriod == nil && limit > 0 && period > 0 {
+		b.setLimit(limit, time.Duration(period)*time.Second)
+	}
+	if errRemaining == nil {
+		b.tokens = math.Min(float64(remaining), b.capacity)
+		b.last = time.Now()
+		if remaining == 0 && errReset == nil {
+			b.blockedUntil = time.Unix(reset, 0)
+		}
+	}
+}
+
+// block holds back every request to the endpoint for d
+func (b *bucket) block(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(b.blockedUntil) {
+		b.blockedUntil = until
+	}
+}
+
+// backoff returns an exponentially growing delay with full jitter
+func backoff(attempt int) time.Duration {
+	ceiling := baseBackoff << attempt
+	if ceiling > maxBackoff || ceiling <= 0 {
+		ceiling = maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// retryAfter reads how long the API asked us to wait, from Retry-After or x-ratelimit-reset
+func retryAfter(header http.Header) time.Duration {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if reset, err := strconv.ParseInt(header.Get("x-ratelimit-reset"), 10, 64); err == nil {
+		if d := time.Until(time.Unix(reset, 0)); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
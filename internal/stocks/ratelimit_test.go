@@ -0,0 +1,37 @@
+package stocks
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDocumentedLimitsCoverEndpoints(t *testing.T) {
+	tests := []struct {
+		method   string
+		endpoint string
+		key      string
+	}{
+		{http.MethodGet, "/equity/pies", "GET /equity/pies"},
+		{http.MethodGet, "/equity/pies/123", "GET /equity/pies/{id}"},
+		{http.MethodPost, "/equity/pies/123", "POST /equity/pies/{id}"},
+		{http.MethodDelete, "/equity/pies/123", "DELETE /equity/pies/{id}"},
+		{http.MethodGet, "/history/exports", "GET /history/exports"},
+		{http.MethodPost, "/history/exports", "POST /history/exports"},
+		{http.MethodGet, "/equity/history/orders?limit=50", "GET /equity/history/orders"},
+		{http.MethodPost, "/equity/orders/market", "POST /equity/orders/market"},
+		{http.MethodPost, "/equity/orders/limit", "POST /equity/orders/limit"},
+		{http.MethodPost, "/equity/orders/stop", "POST /equity/orders/stop"},
+		{http.MethodPost, "/equity/orders/stop_limit", "POST /equity/orders/stop_limit"},
+	}
+
+	for _, tt := range tests {
+		key := endpointKey(tt.method, tt.endpoint)
+		if key != tt.key {
+			t.Errorf("endpointKey(%s %s) = %q, want %q", tt.method, tt.endpoint, key, tt.key)
+			continue
+		}
+		if _, ok := documentedLimits[key]; !ok {
+			t.Errorf("%s has no documented limit", key)
+		}
+	}
+}
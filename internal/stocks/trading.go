@@ -0,0 +1,651 @@
+package stocks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPreviewTTL is how long a dry-run preview can be placed before it must be redone
+const DefaultPreviewTTL = 5 * time.Minute
+
+// maxPriceDeviation is how far the expected price of a market order may be off
+// the live price, so that a mistyped price is refused rather than trusted
+const maxPriceDeviation = 0.05
+
+// Order audit actions and outcomes
+const (
+	AuditPreview = "preview"
+	AuditPlace   = "place"
+	AuditCancel  = "cancel"
+
+	OutcomePreviewed = "previewed"
+	OutcomeRejected  = "rejected"
+	OutcomeSubmitted = "submitted"
+	OutcomePlaced    = "placed"
+	OutcomeFailed    = "failed"
+	OutcomeRefused   = "refused"
+	OutcomeCancelled = "cancelled"
+)
+
+var (
+	// ErrTradingDisabled is returned when orders are not enabled for an account
+	ErrTradingDisabled = errors.New("trading is disabled")
+
+	// ErrGuardrail is returned for orders outside the configured limits
+	ErrGuardrail = errors.New("order blocked by trading guardrails")
+
+	// ErrNoPreview is returned when an order is placed without a valid dry-run preview
+	ErrNoPreview = errors.New("order has no valid dry-run preview")
+)
+
+// TradingConfig enables order placement and sets its limits. Values are in
+// the account currency
+type TradingConfig struct {
+	Enabled        bool
+	Account        string
+	MaxOrderValue  float64
+	MaxDailyValue  float64
+	AllowedTickers []string
+	PreviewTTL     time.Duration
+}
+
+// LoadTradingConfig reads TRADING212_TRADING_ENABLED and, when trading is
+// enabled, the required TRADING212_MAX_ORDER_VALUE, TRADING212_MAX_DAILY_VALUE
+// and TRADING212_ALLOWED_TICKERS, plus the optional TRADING212_TRADING_ACCOUNT
+func LoadTradingConfig() (*TradingConfig, error) {
+	config := &TradingConfig{
+		Enabled:    os.Getenv("TRADING212_TRADING_ENABLED") == "true",
+		Account:    strings.TrimSpace(os.Getenv("TRADING212_TRADING_ACCOUNT")),
+		PreviewTTL: DefaultPreviewTTL,
+	}
+	if !config.Enabled {
+		return config, nil
+	}
+
+	var err error
+	config.MaxOrderValue, err = positiveEnv("TRADING212_MAX_ORDER_VALUE")
+	if err != nil {
+		return nil, err
+	}
+	config.MaxDailyValue, err = positiveEnv("TRADING212_MAX_DAILY_VALUE")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ticker := range strings.Split(os.Getenv("TRADING212_ALLOWED_TICKERS"), ",") {
+		if ticker = strings.TrimSpace(ticker); ticker != "" {
+			config.AllowedTickers = append(config.AllowedTickers, ticker)
+		}
+	}
+	if len(config.AllowedTickers) == 0 {
+		return nil, fmt.Errorf("TRADING212_ALLOWED_TICKERS environment variable is required when trading is enabled")
+	}
+
+	return config, nil
+}
+
+func positiveEnv(name string) (float64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, fmt.Errorf("%s environment variable is required when trading is enabled", name)
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("invalid %s %q, expected a positive amount", name, value)
+	}
+	return number, nil
+}
+
+// OrderAttempt is one entry of the order audit log
+type OrderAttempt struct {
+	Time        time.Time
+	Account     string
+	Action      string
+	Outcome     string
+	PreviewID   string
+	Ticker      string
+	OrderType   OrderType
+	Quantity    float64
+	LimitPrice  float64
+	StopPrice   float64
+	TimeInForce TimeInForce
+	Value       float64
+	Currency    string
+	OrderID     int64
+	Reason      string
+}
+
+// OrderAuditLog stores every order attempt and totals the orders that may
+// have executed, which the daily cap is checked against. LockOrders holds off
+// orders on the account from other processes until the returned unlock is called
+type OrderAuditLog interface {
+	RecordOrder(ctx context.Context, attempt OrderAttempt) error
+	SubmittedValue(ctx context.Context, account string, since time.Time) (float64, error)
+	LockOrders(ctx context.Context, account string) (unlock func(), err error)
+}
+
+// InstrumentLookup finds the instrument of a Trading212 ticker, whose limits orders are checked against
+type InstrumentLookup func(ticker string) (Instrument, bool)
+
+// PriceLookup returns the live price of an instrument in its own currency
+type PriceLookup func(ctx context.Context, ticker string) (float64, error)
+
+// OrderValuer converts an amount in an instrument's currency into the account currency
+type OrderValuer func(ctx context.Context, ticker string, amount float64) (float64, error)
+
+// OrderIntent is an order to preview. A negative quantity or amount sells
+type OrderIntent struct {
+	Ticker      string
+	Type        OrderType
+	Quantity    float64
+	LimitPrice  float64
+	StopPrice   float64
+	TimeInForce TimeInForce
+
+	// Amount sizes a market order by cash value in the instrument currency
+	// instead of by quantity
+	Amount float64
+
+	// Price is the expected price of a market order in the instrument
+	// currency. The preview replaces it with the live price, refusing an
+	// expected price that is far off it
+	Price float64
+}
+
+// OrderPreview is a dry run of an order that passed the guardrails
+type OrderPreview struct {
+	ID         string
+	Intent     OrderIntent
+	Value      float64
+	Currency   string
+	DailyValue float64
+	ExpiresAt  time.Time
+}
+
+// Trader places orders on one account within the configured guardrails and
+// records every attempt in the audit log. A plain Client cannot place orders
+type Trader struct {
+	client   *Client
+	account  string
+	currency string
+	config   TradingConfig
+	allowed  map[string]bool
+	audit    OrderAuditLog
+	lookup   InstrumentLookup
+	price    PriceLookup
+	value    OrderValuer
+
+	mu       sync.Mutex
+	previews map[string]*OrderPreview
+
+	// placing serialises orders within the process; the audit log lock does
+	// the same across processes, so two cannot pass the daily cap together
+	placing sync.Mutex
+}
+
+// NewTrader enables orders on an account, refusing unless trading is enabled
+// for it; currency is the account currency the caps are expressed in
+func NewTrader(account Account, currency string, config *TradingConfig, audit OrderAuditLog, lookup InstrumentLookup, price PriceLookup, value OrderValuer) (*Trader, error) {
+	if config == nil || !config.Enabled {
+		return nil, fmt.Errorf("%w: set TRADING212_TRADING_ENABLED=true to allow orders", ErrTradingDisabled)
+	}
+	if config.Account != "" && !strings.EqualFold(config.Account, account.Name) {
+		return nil, fmt.Errorf("%w for account %s, only %s may trade", ErrTradingDisabled, account.Name, config.Account)
+	}
+	if audit == nil {
+		return nil, fmt.Errorf("%w: an order audit log is required", ErrTradingDisabled)
+	}
+	if lookup == nil {
+		return nil, fmt.Errorf("%w: orders cannot be checked against instrument limits", ErrTradingDisabled)
+	}
+	if price == nil {
+		return nil, fmt.Errorf("%w: market orders cannot be checked against live prices", ErrTradingDisabled)
+	}
+	if value == nil {
+		return nil, fmt.Errorf("%w: orders cannot be valued against the caps", ErrTradingDisabled)
+	}
+
+	allowed := make(map[string]bool, len(config.AllowedTickers))
+	for _, ticker := range config.AllowedTickers {
+		allowed[ticker] = true
+	}
+
+	if config.PreviewTTL <= 0 {
+		config.PreviewTTL = DefaultPreviewTTL
+	}
+
+	return &Trader{
+		client:   NewClient(account.APIKey, account.IsLive),
+		account:  account.Name,
+		currency: currency,
+		config:   *config,
+		allowed:  allowed,
+		audit:    audit,
+		lookup:   lookup,
+		price:    price,
+		value:    value,
+		previews: make(map[string]*OrderPreview),
+	}, nil
+}
+
+// Preview checks an order against the guardrails without sending it. Only a
+// preview can be placed
+func (t *Trader) Preview(ctx context.Context, intent OrderIntent) (*OrderPreview, error) {
+	intent, err := t.prepare(ctx, intent)
+	var preview *OrderPreview
+	if err == nil {
+		preview, err = t.check(ctx, intent)
+	}
+
+	attempt := t.attempt(AuditPreview, intent)
+	if err != nil {
+		attempt.Outcome = OutcomeRejected
+		attempt.Reason = err.Error()
+	} else {
+		attempt.Outcome = OutcomePreviewed
+		attempt.PreviewID = preview.ID
+		attempt.Value = preview.Value
+	}
+	if auditErr := t.audit.RecordOrder(ctx, attempt); auditErr != nil {
+		return nil, fmt.Errorf("failed to audit order preview: %w", auditErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	t.previews[preview.ID] = preview
+	t.mu.Unlock()
+
+	return preview, nil
+}
+
+// Place sends a previewed order after checking the guardrails again, since
+// other orders may have been placed since the preview
+func (t *Trader) Place(ctx context.Context, preview *OrderPreview) (*Order, error) {
+	if preview == nil {
+		return nil, ErrNoPreview
+	}
+
+	t.placing.Lock()
+	defer t.placing.Unlock()
+
+	t.mu.Lock()
+	issued, ok := t.previews[preview.ID]
+	delete(t.previews, preview.ID)
+	t.mu.Unlock()
+
+	attempt := t.attempt(AuditPlace, preview.Intent)
+	attempt.PreviewID = preview.ID
+	attempt.Value = preview.Value
+
+	var err error
+	switch {
+	case !ok || issued != preview:
+		err = ErrNoPreview
+	case time.Now().After(preview.ExpiresAt):
+		err = fmt.Errorf("%w: the preview expired at %s", ErrNoPreview, preview.ExpiresAt.Format("15:04:05"))
+	default:
+		var unlock func()
+		unlock, err = t.audit.LockOrders(ctx, t.account)
+		if err != nil {
+			err = fmt.Errorf("%w: %v", ErrGuardrail, err)
+			break
+		}
+		defer unlock()
+
+		// The caps are checked again at the price the order goes out at
+		intent := preview.Intent
+		if intent.Type == OrderTypeMarket {
+			intent, err = t.marketPrice(ctx, intent)
+		}
+		var checked *OrderPreview
+		if err == nil {
+			checked, err = t.check(ctx, intent)
+		}
+		if err == nil {
+			attempt.Value = checked.Value
+		}
+	}
+	if err != nil {
+		attempt.Outcome = OutcomeRejected
+		attempt.Reason = err.Error()
+		if auditErr := t.audit.RecordOrder(ctx, attempt); auditErr != nil {
+			return nil, fmt.Errorf("failed to audit order: %w", auditErr)
+		}
+		return nil, err
+	}
+
+	// An order that cannot be audited is never sent
+	attempt.Outcome = OutcomeSubmitted
+	if err := t.audit.RecordOrder(ctx, attempt); err != nil {
+		return nil, fmt.Errorf("failed to audit order, not placing it: %w", err)
+	}
+
+	order, err := t.client.placeOrder(ctx, preview.Intent)
+
+	// The outcome is recorded even if the caller has given up waiting
+	auditCtx := context.WithoutCancel(ctx)
+	attempt.Time = time.Now()
+	if err != nil {
+		// Only a refusal proves the order did not execute; a timeout or a
+		// server error may still have placed it, so it keeps counting
+		attempt.Outcome = OutcomeFailed
+		if refused(err) {
+			attempt.Outcome = OutcomeRefused
+		}
+		attempt.Reason = err.Error()
+		if auditErr := t.audit.RecordOrder(auditCtx, attempt); auditErr != nil {
+			return nil, fmt.Errorf("%w (and failed to audit it: %v)", err, auditErr)
+		}
+		return nil, err
+	}
+
+	attempt.Outcome = OutcomePlaced
+	attempt.OrderID = order.ID
+	if err := t.audit.RecordOrder(auditCtx, attempt); err != nil {
+		return order, fmt.Errorf("order %d was placed but could not be audited: %w", order.ID, err)
+	}
+
+	return order, nil
+}
+
+// Cancel cancels a pending order
+func (t *Trader) Cancel(ctx context.Context, orderID int64) error {
+	attempt := t.attempt(AuditCancel, OrderIntent{})
+	attempt.OrderID = orderID
+	attempt.Outcome = OutcomeSubmitted
+	if err := t.audit.RecordOrder(ctx, attempt); err != nil {
+		return fmt.Errorf("failed to audit cancellation, not sending it: %w", err)
+	}
+
+	err := t.client.cancelOrder(ctx, orderID)
+
+	attempt.Time = time.Now()
+	attempt.Outcome = OutcomeCancelled
+	if err != nil {
+		attempt.Outcome = OutcomeFailed
+		attempt.Reason = err.Error()
+	}
+	if auditErr := t.audit.RecordOrder(context.WithoutCancel(ctx), attempt); auditErr != nil && err == nil {
+		return fmt.Errorf("order %d was cancelled but could not be audited: %w", orderID, auditErr)
+	}
+
+	return err
+}
+
+// check applies the guardrails to an order and values it
+func (t *Trader) check(ctx context.Context, intent OrderIntent) (*OrderPreview, error) {
+	if !t.allowed[intent.Ticker] {
+		return nil, fmt.Errorf("%w: %s is not in TRADING212_ALLOWED_TICKERS", ErrGuardrail, intent.Ticker)
+	}
+
+	instrument, ok := t.lookup(intent.Ticker)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not in the instrument catalogue", ErrGuardrail, intent.Ticker)
+	}
+	if err := instrument.ValidateQuantity(intent.Quantity); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGuardrail, err)
+	}
+
+	price, err := orderPrice(intent)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGuardrail, err)
+	}
+
+	value, err := t.value(ctx, intent.Ticker, math.Abs(intent.Quantity)*price)
+	if err != nil {
+		return nil, fmt.Errorf("failed to value order: %w", err)
+	}
+	if value > t.config.MaxOrderValue {
+		return nil, fmt.Errorf("%w: %.2f %s exceeds the %.2f %s order cap", ErrGuardrail, value, t.currency, t.config.MaxOrderValue, t.currency)
+	}
+
+	submitted, err := t.audit.SubmittedValue(ctx, t.account, startOfDay(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to total today's orders: %w", err)
+	}
+	if submitted+value > t.config.MaxDailyValue {
+		return nil, fmt.Errorf("%w: %.2f %s would bring today's orders to %.2f %s, over the %.2f %s daily cap",
+			ErrGuardrail, value, t.currency, submitted+value, t.currency, t.config.MaxDailyValue, t.currency)
+	}
+
+	return &OrderPreview{
+		ID:         newPreviewID(),
+		Intent:     intent,
+		Value:      value,
+		Currency:   t.currency,
+		DailyValue: submitted + value,
+		ExpiresAt:  time.Now().Add(t.config.PreviewTTL),
+	}, nil
+}
+
+// prepare fills in an order's defaults, prices a market order at the live
+// price and sizes a value order. The API only takes quantities, so a cash
+// amount is converted at that price and rounded down to the instrument's
+// quantity precision
+func (t *Trader) prepare(ctx context.Context, intent OrderIntent) (OrderIntent, error) {
+	if intent.Type != OrderTypeMarket && intent.TimeInForce == "" {
+		intent.TimeInForce = TimeInForceDay
+	}
+	if intent.Type == OrderTypeMarket && intent.LimitPrice == 0 && intent.StopPrice == 0 {
+		var err error
+		intent, err = t.marketPrice(ctx, intent)
+		if err != nil {
+			return intent, err
+		}
+	}
+	if intent.Amount == 0 {
+		return intent, nil
+	}
+
+	if intent.Quantity != 0 {
+		return intent, fmt.Errorf("%w: set either a quantity or a cash amount, not both", ErrGuardrail)
+	}
+	if intent.Type != OrderTypeMarket {
+		return intent, fmt.Errorf("%w: only market orders can be sized by cash amount", ErrGuardrail)
+	}
+	if !(intent.Price > 0) {
+		return intent, fmt.Errorf("%w: a cash amount order needs a price to be sized", ErrGuardrail)
+	}
+	instrument, ok := t.lookup(intent.Ticker)
+	if !ok {
+		return intent, fmt.Errorf("%w: %s is not in the instrument catalogue", ErrGuardrail, intent.Ticker)
+	}
+
+	scale := math.Pow10(instrument.QuantityPrecision)
+	quantity := math.Floor(math.Abs(intent.Amount)/intent.Price*scale+1e-9) / scale
+	if quantity == 0 {
+		return intent, fmt.Errorf("%w: %.2f %s buys less than the smallest quantity of %s",
+			ErrGuardrail, math.Abs(intent.Amount), instrument.CurrencyCode, intent.Ticker)
+	}
+	intent.Quantity = math.Copysign(quantity, intent.Amount)
+	return intent, nil
+}
+
+// marketPrice prices a market order at the live price, refusing an expected
+// price more than maxPriceDeviation off it. Trading212 only quotes held
+// instruments, so other instruments have to be bought with a limit order
+func (t *Trader) marketPrice(ctx context.Context, intent OrderIntent) (OrderIntent, error) {
+	live, err := t.price(ctx, intent.Ticker)
+	if err == nil && !(live > 0) {
+		err = fmt.Errorf("invalid price %g", live)
+	}
+	if err != nil {
+		return intent, fmt.Errorf("%w: no live price for %s to check a market order against, use a limit order: %v",
+			ErrGuardrail, intent.Ticker, err)
+	}
+
+	if intent.Price > 0 && math.Abs(intent.Price-live)/live > maxPriceDeviation {
+		return intent, fmt.Errorf("%w: the expected price %g of %s is more than %.0f%% off its live price %g",
+			ErrGuardrail, intent.Price, intent.Ticker, maxPriceDeviation*100, live)
+	}
+
+	intent.Price = live
+	return intent, nil
+}
+
+// refused reports whether Trading212 turned an order down, as opposed to a
+// failure after which the order may still have been placed
+func refused(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 && apiErr.StatusCode != http.StatusRequestTimeout
+}
+
+// orderPrice checks an order's prices and returns the one it is valued at
+func orderPrice(intent OrderIntent) (float64, error) {
+	if intent.Type != OrderTypeMarket && intent.TimeInForce != TimeInForceDay && intent.TimeInForce != TimeInForceGoodTillCancel {
+		return 0, fmt.Errorf("unsupported time in force %q", intent.TimeInForce)
+	}
+
+	switch intent.Type {
+	case OrderTypeMarket:
+		if intent.LimitPrice != 0 || intent.StopPrice != 0 {
+			return 0, fmt.Errorf("a market order takes no limit or stop price")
+		}
+		if !(intent.Price > 0) {
+			return 0, fmt.Errorf("a market order needs a live price to be checked against the caps")
+		}
+		return intent.Price, nil
+	case OrderTypeLimit:
+		if !(intent.LimitPrice > 0) || intent.StopPrice != 0 {
+			return 0, fmt.Errorf("a limit order needs a positive limit price and no stop price")
+		}
+		return intent.LimitPrice, nil
+	case OrderTypeStop:
+		if !(intent.StopPrice > 0) || intent.LimitPrice != 0 {
+			return 0, fmt.Errorf("a stop order needs a positive stop price and no limit price")
+		}
+		return intent.StopPrice, nil
+	case OrderTypeStopLimit:
+		if !(intent.StopPrice > 0) || !(intent.LimitPrice > 0) {
+			return 0, fmt.Errorf("a stop-limit order needs positive stop and limit prices")
+		}
+		return intent.LimitPrice, nil
+	default:
+		return 0, fmt.Errorf("unsupported order type %q", intent.Type)
+	}
+}
+
+// ValidateQuantity checks an order quantity, negative for a sell, against the
+// instrument's minimum, maximum and decimal places
+func (i Instrument) ValidateQuantity(quantity float64) error {
+	size := math.Abs(quantity)
+	if !(size > 0) || math.IsInf(size, 0) {
+		return fmt.Errorf("invalid quantity %g", quantity)
+	}
+	if i.MinTradeQuantity > 0 && size < i.MinTradeQuantity {
+		return fmt.Errorf("%g %s is below the minimum trade quantity of %g", size, i.Ticker, i.MinTradeQuantity)
+	}
+	if i.MaxOpenQuantity > 0 && size > i.MaxOpenQuantity {
+		return fmt.Errorf("%g %s is above the maximum open quantity of %g", size, i.Ticker, i.MaxOpenQuantity)
+	}
+
+	scaled := size * math.Pow10(i.QuantityPrecision)
+	if math.Abs(scaled-math.Round(scaled)) > 1e-6 {
+		return fmt.Errorf("%g %s has more than %d decimal places", size, i.Ticker, i.QuantityPrecision)
+	}
+	return nil
+}
+
+func (t *Trader) attempt(action string, intent OrderIntent) OrderAttempt {
+	return OrderAttempt{
+		Time:        time.Now(),
+		Account:     t.account,
+		Action:      action,
+		Ticker:      intent.Ticker,
+		OrderType:   intent.Type,
+		Quantity:    intent.Quantity,
+		LimitPrice:  intent.LimitPrice,
+		StopPrice:   intent.StopPrice,
+		TimeInForce: intent.TimeInForce,
+		Currency:    t.currency,
+	}
+}
+
+// placeOrder sends an order; only a Trader calls it
+func (c *Client) placeOrder(ctx context.Context, intent OrderIntent) (*Order, error) {
+	var endpoint string
+	var body interface{}
+	switch intent.Type {
+	case OrderTypeMarket:
+		endpoint = "/equity/orders/market"
+		body = MarketOrderRequest{Ticker: intent.Ticker, Quantity: intent.Quantity}
+	case OrderTypeLimit:
+		endpoint = "/equity/orders/limit"
+		body = LimitOrderRequest{
+			Ticker:      intent.Ticker,
+			Quantity:    intent.Quantity,
+			LimitPrice:  intent.LimitPrice,
+			TimeInForce: intent.TimeInForce,
+		}
+	case OrderTypeStop:
+		endpoint = "/equity/orders/stop"
+		body = StopOrderRequest{
+			Ticker:      intent.Ticker,
+			Quantity:    intent.Quantity,
+			StopPrice:   intent.StopPrice,
+			TimeInForce: intent.TimeInForce,
+		}
+	case OrderTypeStopLimit:
+		endpoint = "/equity/orders/stop_limit"
+		body = StopLimitOrderRequest{
+			Ticker:      intent.Ticker,
+			Quantity:    intent.Quantity,
+			StopPrice:   intent.StopPrice,
+			LimitPrice:  intent.LimitPrice,
+			TimeInForce: intent.TimeInForce,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported order type %q", intent.Type)
+	}
+
+	data, err := c.makeRequest(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place %s order: %w", strings.ToLower(string(intent.Type)), err)
+	}
+
+	var order Order
+	if err := json.Unmarshal(data, &order); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal order response: %w", err)
+	}
+
+	return &order, nil
+}
+
+// cancelOrder cancels a pending order; only a Trader calls it
+func (c *Client) cancelOrder(ctx context.Context, orderID int64) error {
+	endpoint := fmt.Sprintf("/equity/orders/%d", orderID)
+	_, err := c.makeRequest(ctx, http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to cancel order %d: %w", orderID, err)
+	}
+
+	return nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func newPreviewID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,232 @@
+package stocks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryAudit is an OrderAuditLog kept in memory, totalling like the database does
+type memoryAudit struct {
+	mu       sync.Mutex
+	attempts []OrderAttempt
+	locked   bool
+}
+
+func (m *memoryAudit) RecordOrder(ctx context.Context, attempt OrderAttempt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts = append(m.attempts, attempt)
+	return nil
+}
+
+func (m *memoryAudit) SubmittedValue(ctx context.Context, account string, since time.Time) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var total float64
+	for _, attempt := range m.attempts {
+		if attempt.Account != account || attempt.Action != AuditPlace || attempt.Time.Before(since) {
+			continue
+		}
+		switch attempt.Outcome {
+		case OutcomeSubmitted:
+			total += attempt.Value
+		case OutcomeRefused:
+			total -= attempt.Value
+		}
+	}
+	return total, nil
+}
+
+func (m *memoryAudit) LockOrders(ctx context.Context, account string) (func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.locked {
+		return nil, fmt.Errorf("another order is being placed on the %s account", account)
+	}
+	m.locked = true
+	return func() {
+		m.mu.Lock()
+		m.locked = false
+		m.mu.Unlock()
+	}, nil
+}
+
+func (m *memoryAudit) outcomes() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var outcomes []string
+	for _, attempt := range m.attempts {
+		if attempt.Action == AuditPlace {
+			outcomes = append(outcomes, attempt.Outcome)
+		}
+	}
+	return outcomes
+}
+
+// testTrader trades AAPL_US_EQ at a live price of 100, with a 300 order cap
+// and a 500 daily cap, against a Trading212 stand-in answering orders with status
+func testTrader(t *testing.T, audit OrderAuditLog, status int) *Trader {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"code":"InsufficientResources","clarification":"not enough funds"}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":1,"status":"NEW"}`)
+	}))
+	t.Cleanup(server.Close)
+
+	config := &TradingConfig{
+		Enabled:        true,
+		MaxOrderValue:  300,
+		MaxDailyValue:  500,
+		AllowedTickers: []string{"AAPL_US_EQ"},
+	}
+	lookup := func(ticker string) (Instrument, bool) {
+		return Instrument{Ticker: ticker, CurrencyCode: "USD", QuantityPrecision: 2}, ticker == "AAPL_US_EQ"
+	}
+	price := func(ctx context.Context, ticker string) (float64, error) {
+		return 100, nil
+	}
+	value := func(ctx context.Context, ticker string, amount float64) (float64, error) {
+		return amount, nil
+	}
+
+	trader, err := NewTrader(Account{Name: "invest"}, "USD", config, audit, lookup, price, value)
+	if err != nil {
+		t.Fatalf("NewTrader failed: %v", err)
+	}
+	trader.client.baseURL = server.URL
+	return trader
+}
+
+func TestMarketOrdersArePricedLive(t *testing.T) {
+	tests := []struct {
+		name   string
+		intent OrderIntent
+		err    string
+		value  float64
+	}{
+		{"no expected price", OrderIntent{Quantity: 2}, "", 200},
+		{"close expected price", OrderIntent{Quantity: 2, Price: 103}, "", 200},
+		{"mistyped expected price", OrderIntent{Quantity: 2, Price: 10}, "off its live price", 0},
+		{"understated to pass the cap", OrderIntent{Quantity: 5, Price: 50}, "off its live price", 0},
+		{"cash amount sized at the live price", OrderIntent{Amount: 250}, "", 250},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trader := testTrader(t, &memoryAudit{}, http.StatusOK)
+			tt.intent.Ticker = "AAPL_US_EQ"
+			tt.intent.Type = OrderTypeMarket
+
+			preview, err := trader.Preview(context.Background(), tt.intent)
+			if tt.err != "" {
+				if !errors.Is(err, ErrGuardrail) || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want a guardrail error about %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Preview failed: %v", err)
+			}
+			if preview.Intent.Price != 100 || preview.Value != tt.value {
+				t.Errorf("priced at %v worth %v, want 100 worth %v", preview.Intent.Price, preview.Value, tt.value)
+			}
+		})
+	}
+}
+
+func TestMarketOrderWithoutLivePriceIsRefused(t *testing.T) {
+	trader := testTrader(t, &memoryAudit{}, http.StatusOK)
+	trader.price = func(ctx context.Context, ticker string) (float64, error) {
+		return 0, ErrNotFound
+	}
+
+	_, err := trader.Preview(context.Background(), OrderIntent{Ticker: "AAPL_US_EQ", Type: OrderTypeMarket, Quantity: 1, Price: 100})
+	if !errors.Is(err, ErrGuardrail) || !strings.Contains(err.Error(), "use a limit order") {
+		t.Errorf("error = %v, want the order refused without a live price", err)
+	}
+
+	// A limit order carries its own price
+	_, err = trader.Preview(context.Background(), OrderIntent{Ticker: "AAPL_US_EQ", Type: OrderTypeLimit, Quantity: 1, LimitPrice: 100})
+	if err != nil {
+		t.Errorf("limit order rejected: %v", err)
+	}
+}
+
+func TestDailyCapCountsOrdersThatMayHaveExecuted(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		outcome string
+		daily   float64
+	}{
+		{"placed", http.StatusOK, OutcomePlaced, 300},
+		{"server error", http.StatusBadGateway, OutcomeFailed, 300},
+		{"refused", http.StatusBadRequest, OutcomeRefused, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &memoryAudit{}
+			trader := testTrader(t, audit, tt.status)
+			ctx := context.Background()
+
+			preview, err := trader.Preview(ctx, OrderIntent{Ticker: "AAPL_US_EQ", Type: OrderTypeMarket, Quantity: 3})
+			if err != nil {
+				t.Fatalf("Preview failed: %v", err)
+			}
+			trader.Place(ctx, preview)
+
+			outcomes := audit.outcomes()
+			if len(outcomes) != 2 || outcomes[0] != OutcomeSubmitted || outcomes[1] != tt.outcome {
+				t.Errorf("outcomes = %v, want submitted then %s", outcomes, tt.outcome)
+			}
+
+			daily, _ := audit.SubmittedValue(ctx, "invest", startOfDay(time.Now()))
+			if daily != tt.daily {
+				t.Errorf("counted %v towards the daily cap, want %v", daily, tt.daily)
+			}
+
+			// A second order of 300 only fits when the first is known not to have executed
+			_, err = trader.Preview(ctx, OrderIntent{Ticker: "AAPL_US_EQ", Type: OrderTypeMarket, Quantity: 3})
+			if capped := errors.Is(err, ErrGuardrail); capped != (tt.daily > 0) {
+				t.Errorf("second order error = %v, want capped %v", err, tt.daily > 0)
+			}
+		})
+	}
+}
+
+func TestPlaceTakesTheOrderLock(t *testing.T) {
+	audit := &memoryAudit{}
+	trader := testTrader(t, audit, http.StatusOK)
+	ctx := context.Background()
+
+	preview, err := trader.Preview(ctx, OrderIntent{Ticker: "AAPL_US_EQ", Type: OrderTypeMarket, Quantity: 1})
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	// Another process holds the lock
+	unlock, _ := audit.LockOrders(ctx, "invest")
+	_, err = trader.Place(ctx, preview)
+	unlock()
+	if !errors.Is(err, ErrGuardrail) || !strings.Contains(err.Error(), "another order") {
+		t.Errorf("error = %v, want the order held off by the lock", err)
+	}
+	if outcomes := audit.outcomes(); len(outcomes) != 1 || outcomes[0] != OutcomeRejected {
+		t.Errorf("outcomes = %v, want one rejection and nothing sent", outcomes)
+	}
+}
@@ -24,24 +24,70 @@ type Position struct {
 	AveragePriceConverted float64 `json:"averagePriceConverted"`
 	CurrentPrice         float64 `json:"currentPrice"`
 	PieQuantity          float64 `json:"pieQuantity"`
-	FxPnl                float64 `json:"fxPnl"`
+	Ppl                  float64 `json:"ppl"`
+	FxPpl                float64 `json:"fxPpl"`
 	InitialFillDate      string  `json:"initialFillDate"`
 	Frontend             string  `json:"frontend"`
 	MaxBuy               float64 `json:"maxBuy"`
 	MaxSell              float64 `json:"maxSell"`
 }
 
+// OrderType is the kind of a Trading212 order
+type OrderType string
+
+const (
+	OrderTypeMarket    OrderType = "MARKET"
+	OrderTypeLimit     OrderType = "LIMIT"
+	OrderTypeStop      OrderType = "STOP"
+	OrderTypeStopLimit OrderType = "STOP_LIMIT"
+)
+
+// TimeInForce is how long a pending order stays open
+type TimeInForce string
+
+const (
+	TimeInForceDay            TimeInForce = "DAY"
+	TimeInForceGoodTillCancel TimeInForce = "GOOD_TILL_CANCEL"
+)
+
+// OrderStatus is where an order is in its life cycle
+type OrderStatus string
+
+const (
+	OrderStatusLocal           OrderStatus = "LOCAL"
+	OrderStatusUnconfirmed     OrderStatus = "UNCONFIRMED"
+	OrderStatusConfirmed       OrderStatus = "CONFIRMED"
+	OrderStatusNew             OrderStatus = "NEW"
+	OrderStatusCancelling      OrderStatus = "CANCELLING"
+	OrderStatusCancelled       OrderStatus = "CANCELLED"
+	OrderStatusPartiallyFilled OrderStatus = "PARTIALLY_FILLED"
+	OrderStatusFilled          OrderStatus = "FILLED"
+	OrderStatusRejected        OrderStatus = "REJECTED"
+	OrderStatusReplacing       OrderStatus = "REPLACING"
+	OrderStatusReplaced        OrderStatus = "REPLACED"
+)
+
+// OrderStrategy says whether an order was sized by quantity or by cash value
+type OrderStrategy string
+
+const (
+	OrderStrategyQuantity OrderStrategy = "QUANTITY"
+	OrderStrategyValue    OrderStrategy = "VALUE"
+)
+
 type Order struct {
-	CreationTime   time.Time `json:"creationTime"`
-	FilledQuantity float64   `json:"filledQuantity"`
-	ID             int64     `json:"id"`
-	LimitPrice     float64   `json:"limitPrice"`
-	Quantity       float64   `json:"quantity"`
-	Status         string    `json:"status"`
-	Strategy       string    `json:"strategy"`
-	Ticker         string    `json:"ticker"`
-	Type           string    `json:"type"`
-	Value          float64   `json:"value"`
+	CreationTime   time.Time     `json:"creationTime"`
+	FilledQuantity float64       `json:"filledQuantity"`
+	FilledValue    float64       `json:"filledValue"`
+	ID             int64         `json:"id"`
+	LimitPrice     float64       `json:"limitPrice"`
+	StopPrice      float64       `json:"stopPrice"`
+	Quantity       float64       `json:"quantity"`
+	Status         OrderStatus   `json:"status"`
+	Strategy       OrderStrategy `json:"strategy"`
+	Ticker         string        `json:"ticker"`
+	Type           OrderType     `json:"type"`
+	Value          float64       `json:"value"`
 }
 
 type MarketOrderRequest struct {
@@ -50,28 +96,63 @@ type MarketOrderRequest struct {
 }
 
 type LimitOrderRequest struct {
-	Ticker     string  `json:"ticker"`
-	Quantity   float64 `json:"quantity"`
-	LimitPrice float64 `json:"limitPrice"`
-	TimeInForce string  `json:"timeInForce"`
+	Ticker      string      `json:"ticker"`
+	Quantity    float64     `json:"quantity"`
+	LimitPrice  float64     `json:"limitPrice"`
+	TimeInForce TimeInForce `json:"timeInForce"`
+}
+
+type StopOrderRequest struct {
+	Ticker      string      `json:"ticker"`
+	Quantity    float64     `json:"quantity"`
+	StopPrice   float64     `json:"stopPrice"`
+	TimeInForce TimeInForce `json:"timeInForce"`
+}
+
+type StopLimitOrderRequest struct {
+	Ticker      string      `json:"ticker"`
+	Quantity    float64     `json:"quantity"`
+	StopPrice   float64     `json:"stopPrice"`
+	LimitPrice  float64     `json:"limitPrice"`
+	TimeInForce TimeInForce `json:"timeInForce"`
 }
 
+// Exchange lists the working schedules of a Trading212 exchange; instruments
+// link to one through WorkingScheduleID
 type Exchange struct {
-	ID           int    `json:"id"`
-	Name         string `json:"name"`
-	WorkingDays  string `json:"workingDays"`
-	TimeZone     string `json:"timeZone"`
-	OpeningTime  string `json:"openingTime"`
-	ClosingTime  string `json:"closingTime"`
-	LunchStart   string `json:"lunchStart"`
-	LunchEnd     string `json:"lunchEnd"`
-	OpeningDays  string `json:"openingDays"`
-	ClosingDays  string `json:"closingDays"`
+	ID               int               `json:"id"`
+	Name             string            `json:"name"`
+	WorkingSchedules []WorkingSchedule `json:"workingSchedules"`
 }
 
+// WorkingSchedule is a timeline of session events over the coming days
+type WorkingSchedule struct {
+	ID         int         `json:"id"`
+	TimeEvents []TimeEvent `json:"timeEvents"`
+}
+
+// TimeEvent marks a change in the trading session, such as the market opening
+type TimeEvent struct {
+	Date time.Time `json:"date"`
+	Type string    `json:"type"`
+}
+
+// Time event types
+const (
+	EventOpen            = "OPEN"
+	EventClose           = "CLOSE"
+	EventBreakStart      = "BREAK_START"
+	EventBreakEnd        = "BREAK_END"
+	EventPreMarketOpen   = "PRE_MARKET_OPEN"
+	EventAfterHoursOpen  = "AFTER_HOURS_OPEN"
+	EventAfterHoursClose = "AFTER_HOURS_CLOSE"
+	EventOvernightOpen   = "OVERNIGHT_OPEN"
+)
+
 type Instrument struct {
 	Ticker              string  `json:"ticker"`
 	Name                string  `json:"name"`
+	ShortName           string  `json:"shortName"`
 	Type                string  `json:"type"`
 	CurrencyCode        string  `json:"currencyCode"`
 	ISIN                string  `json:"isin"`
@@ -85,7 +166,8 @@ type Instrument struct {
 type Dividend struct {
 	Ticker         string    `json:"ticker"`
 	Type           string    `json:"type"`
-	CashAmount     float64   `json:"cashAmount"`
+	CashAmount     float64   `json:"amount"`
+	AmountInEuro   float64   `json:"amountInEuro"`
 	Quantity       float64   `json:"quantity"`
 	Price          float64   `json:"price"`
 	GrossAmountPerShare float64 `json:"grossAmountPerShare"`
@@ -0,0 +1,263 @@
+package valuation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"investment-tracker/internal/crypto"
+	"investment-tracker/internal/pricecache"
+	"investment-tracker/internal/stocks"
+	"log"
+	"time"
+)
+
+// cryptoQuotes serves crypto prices from the cache, whichever provider in the
+// chain produced them, and only asks the chain for symbols that have expired
+func (e *Engine) cryptoQuotes(ctx context.Context, symbols []string, currency string) (*crypto.Quotes, error) {
+	quotes := crypto.NewQuotes()
+
+	var missing []string
+	for _, symbol := range symbols {
+		if quote, ok := e.cachedCryptoQuote(symbol, currency); ok {
+			quotes.Prices[symbol] = quote
+			continue
+		}
+		missing = append(missing, symbol)
+	}
+
+	if len(missing) == 0 {
+		return quotes, nil
+	}
+
+	fetched, err := e.cryptoPrices.GetPrices(ctx, missing, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	for symbol, quote := range fetched.Prices {
+		quotes.Prices[symbol] = quote
+		e.store(quote.Source, symbol, currency, pricecache.Entry{
+			Price:     quote.Price,
+			Currency:  quote.Currency,
+			Timestamp: quote.Timestamp,
+		})
+	}
+	for symbol, err := range fetched.Errors {
+		quotes.Errors[symbol] = err
+	}
+
+	return quotes, nil
+}
+
+func (e *Engine) cachedCryptoQuote(symbol, currency string) (*crypto.Quote, bool) {
+	for _, source := range e.cryptoPrices.Sources() {
+		if entry, ok := e.cache.Get(source, symbol, currency); ok {
+			return &crypto.Quote{
+				Symbol:    symbol,
+				Price:     entry.Price,
+				Currency:  entry.Currency,
+				Source:    source,
+				Timestamp: entry.Timestamp,
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// bullionPrice returns the spot price of a metal, from the cache when fresh
+func (e *Engine) bullionPrice(ctx context.Context, metal, currency string) (pricecache.Entry, error) {
+	if entry, ok := e.cache.Get("goldapi", metal, currency); ok {
+		return entry, nil
+	}
+
+	if e.bullion == nil {
+		return pricecache.Entry{}, e.bullionErr
+	}
+
+	response, err := e.bullion.GetBullionPrices(ctx, metal, currency)
+	if err != nil {
+		return pricecache.Entry{}, err
+	}
+
+	entry := pricecache.Entry{
+		Price:     response.Price,
+		Currency:  currency,
+		Timestamp: time.Unix(response.Timestamp, 0),
+	}
+	e.store("goldapi", metal, currency, entry)
+
+	return entry, nil
+}
+
+// trading212Total returns the account total in the account currency, from the
+// cache when fresh, with the full cash split kept in the entry data
+func (e *Engine) trading212Total(ctx context.Context, account trading212Account) (pricecache.Entry, error) {
+	key := "account/" + account.name
+	if entry, ok := e.cache.Get("trading212", key, ""); ok {
+		return entry, nil
+	}
+
+	if account.client == nil {
+		return pricecache.Entry{}, fmt.Errorf("failed to create Trading212 client: %w", e.t212Err)
+	}
+
+	// Total cash includes both free cash and the invested amount
+	cash, err := account.client.GetAccountCash(ctx)
+	if err != nil {
+		return pricecache.Entry{}, fmt.Errorf("failed to get account cash: %w", err)
+	}
+
+	entry := pricecache.Entry{
+		Price:     cash.Total,
+		Currency:  cash.CurrencyCode,
+		Timestamp: time.Now(),
+	}
+	if data, err := json.Marshal(cash); err == nil {
+		entry.Data = data
+	}
+	e.store("trading212", key, "", entry)
+
+	return entry, nil
+}
+
+// cachedPie is a pie as kept in the price cache, in the account currency
+type cachedPie struct {
+	ID       int64   `json:"id"`
+	Name     string  `json:"name"`
+	Invested float64 `json:"invested"`
+	Value    float64 `json:"value"`
+	Result   float64 `json:"result"`
+	Cash     float64 `json:"cash"`
+	Progress float64 `json:"progress"`
+}
+
+// pieNamesBudget caps the time spent naming pies in one valuation. Names need
+// a details request per pie, which Trading212 limits to one every five seconds,
+// so the names of a large account are collected over several runs
+const pieNamesBudget = 20 * time.Second
+
+// trading212Pies returns every pie with its name, from the cache when fresh.
+// Pies whose name is not known yet default to their id
+func (e *Engine) trading212Pies(ctx context.Context, p *pool, account trading212Account, currency string) ([]cachedPie, error) {
+	key := "pies/" + account.name
+	if entry, ok := e.cache.Get("trading212", key, ""); ok {
+		var pies []cachedPie
+		if err := json.Unmarshal(entry.Data, &pies); err == nil {
+			return pies, nil
+		}
+	}
+
+	if account.client == nil {
+		return nil, fmt.Errorf("failed to create Trading212 client: %w", e.t212Err)
+	}
+
+	var summaries []stocks.Pie
+	err := p.do(ctx, func() error {
+		var err error
+		summaries, err = account.client.GetPies(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pies := make([]cachedPie, len(summaries))
+	for i, pie := range summaries {
+		pies[i] = cachedPie{
+			ID:       pie.ID,
+			Invested: pie.Result.PriceAvgInvestedValue,
+			Value:    pie.Result.PriceAvgValue,
+			Result:   pie.Result.PriceAvgResult,
+			Cash:     pie.Cash,
+			Progress: pie.Progress,
+		}
+	}
+
+	if err := e.namePies(ctx, p, account, pies); err != nil {
+		// Unnamed pies are still worth reporting, but not worth caching
+		log.Printf("Warning: Could not get all %s pie names: %v", account.name, err)
+		return pies, nil
+	}
+
+	data, err := json.Marshal(pies)
+	if err == nil {
+		e.store("trading212", key, "", pricecache.Entry{
+			Currency:  currency,
+			Timestamp: time.Now(),
+			Data:      data,
+		})
+	}
+
+	return pies, nil
+}
+
+// namePies names pies from the long-lived name cache and fetches the names it
+// lacks one pie at a time, until pieNamesBudget or the deadline runs out.
+// Names fetched before running out are cached for the next run
+func (e *Engine) namePies(ctx context.Context, p *pool, account trading212Account, pies []cachedPie) error {
+	key := "pie-names/" + account.name
+
+	names := make(map[int64]string)
+	if entry, ok := e.cache.Get("trading212-pie-names", key, ""); ok {
+		if err := json.Unmarshal(entry.Data, &names); err != nil {
+			names = make(map[int64]string)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pieNamesBudget)
+	defer cancel()
+
+	known := make(map[int64]string, len(pies))
+	fetched := false
+	var err error
+	for i := range pies {
+		pie := &pies[i]
+		if name, ok := names[pie.ID]; ok {
+			pie.Name = name
+			known[pie.ID] = name
+			continue
+		}
+
+		pie.Name = fmt.Sprintf("Pie %d", pie.ID)
+		if err != nil {
+			continue
+		}
+
+		var details *stocks.PieDetails
+		err = p.do(ctx, func() error {
+			var err error
+			details, err = account.client.GetPie(ctx, pie.ID)
+			return err
+		})
+		if err != nil {
+			err = fmt.Errorf("pie %d: %w", pie.ID, err)
+			continue
+		}
+		if details.Settings.Name != "" {
+			pie.Name = details.Settings.Name
+		}
+		known[pie.ID] = pie.Name
+		fetched = true
+	}
+
+	if !fetched {
+		return err
+	}
+
+	// Only current pies are kept, so deleted pies drop out of the cache
+	if data, err := json.Marshal(known); err == nil {
+		e.store("trading212-pie-names", key, "", pricecache.Entry{
+			Timestamp: time.Now(),
+			Data:      data,
+		})
+	}
+
+	return err
+}
+
+// store caches a price; a cache write failure never fails the valuation
+func (e *Engine) store(source, symbol, currency string, entry pricecache.Entry) {
+	if err := e.cache.Put(source, symbol, currency, entry); err != nil {
+		log.Printf("Warning: Could not cache %s %s price: %v", source, symbol, err)
+	}
+}
@@ -0,0 +1,70 @@
+package valuation
+
+import (
+	"context"
+	"investment-tracker/internal/portfolio"
+	"log"
+)
+
+// Performance compares what holdings cost with what they are worth, in the valuation currency
+type Performance struct {
+	Cost  float64
+	Value float64
+}
+
+// PnL returns the unrealised result
+func (p Performance) PnL() float64 {
+	return p.Value - p.Cost
+}
+
+// PnLPercent returns the unrealised result as a percentage of the cost
+func (p Performance) PnLPercent() float64 {
+	if p.Cost == 0 {
+		return 0
+	}
+	return p.PnL() / p.Cost * 100
+}
+
+// Performance returns the line's cost and value, and false without a cost basis
+func (l Line) Performance() (Performance, bool) {
+	if !l.HasCost {
+		return Performance{}, false
+	}
+	return Performance{Cost: l.Cost, Value: l.Value}, true
+}
+
+// ClassPerformance sums the lines of a class that have a cost basis, and
+// returns false when none has
+func (v *Valuation) ClassPerformance(class AssetClass) (Performance, bool) {
+	var total Performance
+	var ok bool
+	for _, line := range v.LinesFor(class) {
+		if line.HasCost {
+			total.Cost += line.Cost
+			total.Value += line.Value
+			ok = true
+		}
+	}
+	return total, ok
+}
+
+// addHolding adds a line for a holdings file asset, with its cost basis when
+// the asset is held as lots
+func (e *Engine) addHolding(ctx context.Context, v *Valuation, line Line, asset portfolio.Asset) {
+	if asset.HasLots() {
+		cost, err := e.costBasis(ctx, v, asset)
+		if err != nil {
+			log.Printf("Warning: Could not get the cost basis of %s: %v", line.Symbol, err)
+		} else {
+			line.Cost = cost
+			line.HasCost = true
+		}
+	}
+	v.Add(line)
+}
+
+// costBasis converts the cost of every lot into the valuation currency at the
+// rate of the day it was acquired
+func (e *Engine) costBasis(ctx context.Context, v *Valuation, asset portfolio.Asset) (float64, error) {
+	return CostBasis(ctx, e.converter, v.Currency, asset.Lots, v.Timestamp)
+}
@@ -0,0 +1,459 @@
+package valuation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"investment-tracker/internal/bullion"
+	"investment-tracker/internal/conversion"
+	"investment-tracker/internal/crypto"
+	"investment-tracker/internal/instruments"
+	"investment-tracker/internal/portfolio"
+	"investment-tracker/internal/pricecache"
+	"investment-tracker/internal/stocks"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHoldingsPath = "config/holdings.json"
+	DefaultBaseCurrency = "BGN"
+
+	// DefaultConcurrency bounds the price requests in flight during one valuation
+	DefaultConcurrency = 4
+
+	// DefaultTimeout bounds a whole valuation, so a hung endpoint cannot stall the daily job
+	DefaultTimeout = 60 * time.Second
+)
+
+// Engine values the holdings and the Trading212 account
+type Engine struct {
+	holdingsPath   string
+	holdingsSource string
+	holdingsStore  HoldingsStore
+	currency       string
+	converter      *conversion.Converter
+	cryptoPrices   *crypto.Chain
+	bullion        *bullion.Client
+	bullionErr     error
+	trading212     []trading212Account
+	t212Err        error
+	cache          *pricecache.Cache
+	instruments    *instruments.Catalogue
+	history        PriceHistory
+	concurrency    int
+	timeout        time.Duration
+}
+
+func NewEngine(holdingsPath string) (*Engine, error) {
+	cryptoPrices, err := crypto.NewChainFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure crypto prices: %w", err)
+	}
+
+	// CoinGecko and Binance do not quote every currency, BGN included, so
+	// they price in USD and convert rather than dropping out of the chain
+	converter := conversion.Default()
+	cryptoPrices.ConvertWith(func(ctx context.Context, from, to string) (float64, error) {
+		return converter.Rate(ctx, from, to, time.Now())
+	})
+
+	cache, err := pricecache.OpenFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open price cache: %w", err)
+	}
+
+	catalogue, err := instruments.OpenFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open instrument catalogue: %w", err)
+	}
+
+	holdingsSource, err := HoldingsSource()
+	if err != nil {
+		return nil, err
+	}
+
+	// A missing bullion key only fails the bullion source, not the whole valuation
+	bullionClient, bullionErr := bullion.NewClientFromEnv()
+	trading212, t212Err := trading212Accounts()
+
+	concurrency := DefaultConcurrency
+	if value := os.Getenv("VALUATION_CONCURRENCY"); value != "" {
+		concurrency, err = strconv.Atoi(value)
+		if err != nil || concurrency < 1 {
+			return nil, fmt.Errorf("invalid VALUATION_CONCURRENCY %q, expected a positive number", value)
+		}
+	}
+
+	timeout := DefaultTimeout
+	if value := os.Getenv("VALUATION_TIMEOUT"); value != "" {
+		timeout, err = time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid VALUATION_TIMEOUT %q, expected a duration such as 45s", value)
+		}
+	}
+
+	return &Engine{
+		holdingsPath:   holdingsPath,
+		holdingsSource: holdingsSource,
+		currency:       BaseCurrency(),
+		converter:      converter,
+		cryptoPrices:   cryptoPrices,
+		bullion:        bullionClient,
+		bullionErr:     bullionErr,
+		trading212:     trading212,
+		t212Err:        t212Err,
+		cache:          cache,
+		instruments:    catalogue,
+		concurrency:    concurrency,
+		timeout:        timeout,
+	}, nil
+}
+
+// trading212Account is a configured Trading212 account and its client
+type trading212Account struct {
+	name   string
+	client *stocks.Client
+}
+
+// source names the account's price source, keeping "trading212" for a single account
+func (a trading212Account) source() string {
+	if a.name == stocks.DefaultAccount {
+		return "trading212"
+	}
+	return "trading212/" + a.name
+}
+
+// trading212Accounts creates a client per configured account. Without a valid
+// configuration the default account is still returned, without a client, so
+// that it fails on its own and can fall back to its last known value
+func trading212Accounts() ([]trading212Account, error) {
+	accounts, err := stocks.LoadAccounts()
+	if err != nil {
+		return []trading212Account{{name: stocks.DefaultAccount}}, err
+	}
+
+	clients := make([]trading212Account, len(accounts))
+	for i, account := range accounts {
+		clients[i] = trading212Account{
+			name:   account.Name,
+			client: stocks.NewClient(account.APIKey, account.IsLive),
+		}
+	}
+	return clients, nil
+}
+
+// ForceRefresh bypasses cached prices for this engine; fresh prices are still cached
+func (e *Engine) ForceRefresh() {
+	e.cache.ForceRefresh()
+}
+
+// BaseCurrency returns the reporting currency from BASE_CURRENCY, defaulting to BGN
+func BaseCurrency() string {
+	currency := strings.ToUpper(strings.TrimSpace(os.Getenv("BASE_CURRENCY")))
+	if currency == "" {
+		return DefaultBaseCurrency
+	}
+	return currency
+}
+
+// Value fetches current prices and returns the full portfolio valuation
+func (e *Engine) Value(ctx context.Context) (*Valuation, error) {
+	h, err := e.holdings(ctx, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load holdings: %w", err)
+	}
+
+	sources := []source{
+		{ClassCrypto, e.cryptoPrices.Name(), func(ctx context.Context, p *pool, v *Valuation) error {
+			return e.valueCrypto(ctx, p, v, h)
+		}},
+		{ClassBullion, "goldapi", func(ctx context.Context, p *pool, v *Valuation) error {
+			return e.valueBullion(ctx, p, v, h)
+		}},
+	}
+	sources = append(sources, e.trading212Sources(e.valueTrading212)...)
+	sources = append(sources, e.manualSources(h, e.valueEquities)...)
+
+	return e.collect(ctx, New(e.currency), sources...)
+}
+
+// trading212Sources values each Trading212 account as a source of its own,
+// so one failing account does not hide the others
+func (e *Engine) trading212Sources(value func(ctx context.Context, p *pool, v *Valuation, account trading212Account) error) []source {
+	sources := make([]source, len(e.trading212))
+	for i, account := range e.trading212 {
+		sources[i] = source{ClassStocks, account.source(), func(ctx context.Context, p *pool, v *Valuation) error {
+			return value(ctx, p, v, account)
+		}}
+	}
+	return sources
+}
+
+// source values the holdings of one asset class
+type source struct {
+	class AssetClass
+	name  string
+	value func(ctx context.Context, p *pool, v *Valuation) error
+}
+
+// sourceKey carries the name of the source being valued, so that stale
+// lines count against their own source and not every source of the class
+type sourceKey struct{}
+
+// collect values all sources side by side under the engine deadline and
+// records each outcome; a failing source does not abort the run
+func (e *Engine) collect(ctx context.Context, v *Valuation, sources ...source) (*Valuation, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	p := newPool(e.concurrency)
+	errs := make([]error, len(sources))
+
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = src.value(context.WithValue(ctx, sourceKey{}, src.name), p, v)
+		}()
+	}
+	wg.Wait()
+
+	v.sortLines()
+	for i, src := range sources {
+		v.Record(src.class, src.name, errs[i])
+	}
+
+	return e.finish(v)
+}
+
+// finish logs failed sources and rejects a valuation in which every source failed
+func (e *Engine) finish(v *Valuation) (*Valuation, error) {
+	failed := v.Failed()
+	for _, source := range failed {
+		log.Printf("Warning: Could not get %s %s value: %s", source.Source, source.Class, source.Error)
+	}
+
+	if len(failed) == len(v.Sources) {
+		return nil, fmt.Errorf("all price sources failed")
+	}
+
+	return v, nil
+}
+
+func (e *Engine) valueCrypto(ctx context.Context, p *pool, v *Valuation, h *portfolio.Holdings) error {
+	symbols := sortedKeys(h.Crypto)
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	// One batch request per provider prices every coin in the valuation currency
+	var quotes *crypto.Quotes
+	err := p.do(ctx, func() error {
+		var err error
+		quotes, err = e.cryptoQuotes(ctx, symbols, v.Currency)
+		return err
+	})
+	if err != nil {
+		quotes = crypto.NewQuotes()
+		for _, symbol := range symbols {
+			quotes.Errors[symbol] = err
+		}
+	}
+
+	var errs []error
+	for _, symbol := range symbols {
+		asset := h.Crypto[symbol]
+		amount := asset.Quantity()
+
+		quote, ok := quotes.Prices[symbol]
+		if !ok {
+			line, err := e.lastKnown(ctx, v, ClassCrypto, symbol, amount, quotes.Errors[symbol])
+			if err != nil {
+				errs = append(errs, fmt.Errorf("could not get price for %s: %w", symbol, err))
+				continue
+			}
+			e.addHolding(ctx, v, *line, asset)
+			continue
+		}
+
+		e.addHolding(ctx, v, Line{
+			Class:     ClassCrypto,
+			Symbol:    symbol,
+			Amount:    amount,
+			Price:     quote.Price,
+			Value:     amount * quote.Price,
+			Currency:  v.Currency,
+			Source:    quote.Source,
+			Timestamp: quote.Timestamp,
+		}, asset)
+	}
+
+	return errors.Join(errs...)
+}
+
+// valueBullion prices every metal concurrently
+func (e *Engine) valueBullion(ctx context.Context, p *pool, v *Valuation, h *portfolio.Holdings) error {
+	return p.each(ctx, sortedKeys(h.Bullion), func(metal string) error {
+		asset := h.Bullion[metal]
+		amount := asset.Quantity()
+
+		price, timestamp, err := e.liveBullionPrice(ctx, v, metal)
+		if err != nil {
+			line, err := e.lastKnown(ctx, v, ClassBullion, metal, amount, err)
+			if err != nil {
+				return fmt.Errorf("could not get price for %s: %w", metal, err)
+			}
+			e.addHolding(ctx, v, *line, asset)
+			return nil
+		}
+
+		e.addHolding(ctx, v, Line{
+			Class:     ClassBullion,
+			Symbol:    metal,
+			Amount:    amount,
+			Price:     price,
+			Value:     amount * price,
+			Currency:  v.Currency,
+			Source:    "goldapi",
+			Timestamp: timestamp,
+		}, asset)
+		return nil
+	})
+}
+
+// liveBullionPrice returns the USD spot price of a metal converted into the valuation currency
+func (e *Engine) liveBullionPrice(ctx context.Context, v *Valuation, metal string) (float64, time.Time, error) {
+	quote, err := e.bullionPrice(ctx, metal, "USD")
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	rate, err := e.rate(ctx, v, quote.Currency)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return quote.Price * rate, quote.Timestamp, nil
+}
+
+// valueTrading212 values one account as a line named after it, plus its cash
+// split, positions and pies
+func (e *Engine) valueTrading212(ctx context.Context, p *pool, v *Valuation, account trading212Account) error {
+	var total pricecache.Entry
+	var rate float64
+	err := p.do(ctx, func() error {
+		var err error
+		total, rate, err = e.liveTrading212Total(ctx, v, account)
+		return err
+	})
+	if err != nil {
+		line, err := e.lastKnown(ctx, v, ClassStocks, account.name, 1, err)
+		if err != nil {
+			return err
+		}
+		v.Add(*line)
+		return nil
+	}
+
+	v.Add(Line{
+		Class:     ClassStocks,
+		Symbol:    account.name,
+		Amount:    1,
+		Price:     total.Price * rate,
+		Value:     total.Price * rate,
+		Currency:  v.Currency,
+		Source:    account.source(),
+		Timestamp: total.Timestamp,
+	})
+
+	e.addCash(v, account, total, rate)
+	e.addPositions(ctx, p, v, account, rate)
+
+	// The pie breakdown is informational; failing to get it leaves the total intact
+	pies, err := e.trading212Pies(ctx, p, account, total.Currency)
+	if err != nil {
+		log.Printf("Warning: Could not get %s pies: %v", account.name, err)
+		return nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, pie := range pies {
+		v.Pies = append(v.Pies, Pie{
+			Account:  e.accountLabel(account),
+			ID:       pie.ID,
+			Name:     pie.Name,
+			Invested: pie.Invested * rate,
+			Value:    pie.Value * rate,
+			Result:   pie.Result * rate,
+			Cash:     pie.Cash * rate,
+			Progress: pie.Progress,
+		})
+	}
+
+	return nil
+}
+
+// accountLabel names an account in breakdowns; a single account needs no name
+func (e *Engine) accountLabel(account trading212Account) string {
+	if len(e.trading212) == 1 {
+		return ""
+	}
+	return account.name
+}
+
+func (e *Engine) liveTrading212Total(ctx context.Context, v *Valuation, account trading212Account) (pricecache.Entry, float64, error) {
+	total, err := e.trading212Total(ctx, account)
+	if err != nil {
+		return pricecache.Entry{}, 0, err
+	}
+
+	rate, err := e.rate(ctx, v, total.Currency)
+	if err != nil {
+		return pricecache.Entry{}, 0, err
+	}
+
+	return total, rate, nil
+}
+
+// rate returns the rate from a quote currency into the valuation currency,
+// recording it on the valuation so snapshots keep the rate actually used
+func (e *Engine) rate(ctx context.Context, v *Valuation, from string) (float64, error) {
+	if from == v.Currency {
+		return 1, nil
+	}
+
+	key := from + "/" + v.Currency
+	v.mu.Lock()
+	rate, ok := v.Rates[key]
+	v.mu.Unlock()
+	if ok {
+		return rate, nil
+	}
+
+	rate, err := e.converter.Rate(ctx, from, v.Currency, v.Timestamp)
+	if err != nil {
+		return 0, err
+	}
+
+	v.mu.Lock()
+	v.Rates[key] = rate
+	v.mu.Unlock()
+	return rate, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,130 @@
+package valuation
+
+import (
+	"context"
+	"encoding/json"
+	"investment-tracker/internal/conversion"
+	"investment-tracker/internal/crypto"
+	"investment-tracker/internal/fx"
+	"investment-tracker/internal/portfolio"
+	"investment-tracker/internal/pricecache"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// knownPrices is a PriceHistory of fixed last known prices
+type knownPrices map[string]KnownPrice
+
+func (k knownPrices) LastPrice(ctx context.Context, class AssetClass, symbol string, before time.Time) (*KnownPrice, error) {
+	known, ok := k[string(class)+"/"+symbol]
+	if !ok {
+		return nil, nil
+	}
+	return &known, nil
+}
+
+func standIn(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// testEngine builds an engine around the given crypto providers, with an
+// empty price cache and an ECB stand-in that is never reachable
+func testEngine(t *testing.T, currency string, history PriceHistory, providers ...crypto.PriceProvider) *Engine {
+	t.Helper()
+
+	rates := fx.NewService(fx.NewECBProvider(standIn(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})))
+
+	return &Engine{
+		currency:     currency,
+		converter:    conversion.NewConverter(rates),
+		cryptoPrices: crypto.NewChain(providers...),
+		cache:        pricecache.Open(filepath.Join(t.TempDir(), "prices.json")),
+		history:      history,
+		concurrency:  2,
+		timeout:      5 * time.Second,
+	}
+}
+
+// valueCrypto values only the crypto section of the holdings
+func valueCrypto(e *Engine, h *portfolio.Holdings) (*Valuation, error) {
+	return e.collect(context.Background(), New(e.currency), source{ClassCrypto, e.cryptoPrices.Name(), func(ctx context.Context, p *pool, v *Valuation) error {
+		return e.valueCrypto(ctx, p, v, h)
+	}})
+}
+
+func TestCryptoFallsBackThroughChainToLastKnownPrice(t *testing.T) {
+	cmc := standIn(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"status":{"error_code":1008}}`, http.StatusTooManyRequests)
+	})
+	gecko := standIn(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/simple/price":
+			writeJSON(w, map[string]map[string]float64{
+				"bitcoin": {"usd": 60000, "last_updated_at": 1700000000},
+			})
+		default:
+			writeJSON(w, map[string]interface{}{"coins": []interface{}{}})
+		}
+	})
+
+	observed := time.Now().Add(-48 * time.Hour)
+	history := knownPrices{
+		"crypto/ETH": {Price: 2500, Currency: "USD", AsOf: observed},
+	}
+
+	e := testEngine(t, "USD", history, crypto.NewCoinMarketCap("test", cmc), crypto.NewCoinGecko("", gecko))
+	v, err := valueCrypto(e, &portfolio.Holdings{
+		Crypto: map[string]portfolio.Asset{
+			"BTC": {Amount: 0.5},
+			"ETH": {Amount: 2},
+		},
+	})
+	if err != nil {
+		t.Fatalf("valuation failed: %v", err)
+	}
+
+	lines := v.LinesFor(ClassCrypto)
+	if len(lines) != 2 {
+		t.Fatalf("got %d crypto lines, want 2", len(lines))
+	}
+
+	btc, eth := lines[0], lines[1]
+	if btc.Source != "coingecko" || btc.Value != 30000 || btc.Stale {
+		t.Errorf("BTC = %v from %s (stale %v), want a live 30000 from coingecko", btc.Value, btc.Source, btc.Stale)
+	}
+	if eth.Source != "history" || eth.Value != 5000 || !eth.Stale || !eth.Timestamp.Equal(observed) {
+		t.Errorf("ETH = %v from %s (stale %v, %s), want a stale 5000 from history", eth.Value, eth.Source, eth.Stale, eth.Timestamp)
+	}
+
+	if len(v.Sources) != 1 || v.Sources[0].Status != StatusStale {
+		t.Errorf("sources = %+v, want one stale crypto source", v.Sources)
+	}
+}
+
+func TestCryptoWithoutLastKnownPriceFails(t *testing.T) {
+	down := standIn(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	})
+
+	e := testEngine(t, "USD", knownPrices{}, crypto.NewCoinMarketCap("test", down), crypto.NewBinance(down))
+	_, err := valueCrypto(e, &portfolio.Holdings{
+		Crypto: map[string]portfolio.Asset{"BTC": {Amount: 1}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "all price sources failed") {
+		t.Errorf("error = %v, want every source to have failed", err)
+	}
+}
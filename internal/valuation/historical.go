@@ -0,0 +1,148 @@
+package valuation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"investment-tracker/internal/portfolio"
+	"time"
+)
+
+// errNoTrading212History is returned because Trading212 only reports the current account value
+var errNoTrading212History = errors.New("Trading212 has no historical account values")
+
+// ValueAt reconstructs the valuation at the end of a past day from historical
+// prices and exchange rates. Amounts come from the holdings as stored on the
+// day, or from the current holdings file, counting only lots acquired by the
+// day; the Trading212 value comes from the last snapshot before the day,
+// marked stale
+func (e *Engine) ValueAt(ctx context.Context, date time.Time) (*Valuation, error) {
+	v := New(e.currency)
+	v.Timestamp = time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 0, time.UTC)
+	v.Reconstructed = true
+
+	h, err := e.holdings(ctx, v.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load holdings: %w", err)
+	}
+
+	sources := []source{
+		{ClassCrypto, e.cryptoPrices.Name(), func(ctx context.Context, p *pool, v *Valuation) error {
+			return e.valueCryptoAt(ctx, p, v, h)
+		}},
+		{ClassBullion, "goldapi", func(ctx context.Context, p *pool, v *Valuation) error {
+			return e.valueBullionAt(ctx, p, v, h)
+		}},
+	}
+	sources = append(sources, e.trading212Sources(e.valueTrading212At)...)
+	sources = append(sources, e.manualSources(h, e.valueEquitiesAt)...)
+
+	return e.collect(ctx, v, sources...)
+}
+
+// valueCryptoAt prices every coin concurrently, since history has no batch endpoint
+func (e *Engine) valueCryptoAt(ctx context.Context, p *pool, v *Valuation, h *portfolio.Holdings) error {
+	return p.each(ctx, sortedKeys(h.Crypto), func(symbol string) error {
+		asset, held := h.Crypto[symbol].HeldOn(v.Timestamp)
+		if !held {
+			return nil
+		}
+		amount := asset.Quantity()
+
+		line, err := e.historicalCryptoLine(ctx, v, symbol, amount)
+		if err != nil {
+			line, err = e.lastKnown(ctx, v, ClassCrypto, symbol, amount, err)
+			if err != nil {
+				return fmt.Errorf("could not get price for %s: %w", symbol, err)
+			}
+		}
+		e.addHolding(ctx, v, *line, asset)
+		return nil
+	})
+}
+
+// historicalCryptoLine prices a coin in USD on the valuation day and converts
+// it at that day's reference rate
+func (e *Engine) historicalCryptoLine(ctx context.Context, v *Valuation, symbol string, amount float64) (*Line, error) {
+	quote, err := e.cryptoPrices.GetHistoricalPrice(ctx, symbol, "USD", v.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := e.rate(ctx, v, quote.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Line{
+		Class:     ClassCrypto,
+		Symbol:    symbol,
+		Amount:    amount,
+		Price:     quote.Price * rate,
+		Value:     amount * quote.Price * rate,
+		Currency:  v.Currency,
+		Source:    quote.Source,
+		Timestamp: quote.Timestamp,
+	}, nil
+}
+
+func (e *Engine) valueBullionAt(ctx context.Context, p *pool, v *Valuation, h *portfolio.Holdings) error {
+	return p.each(ctx, sortedKeys(h.Bullion), func(metal string) error {
+		asset, held := h.Bullion[metal].HeldOn(v.Timestamp)
+		if !held {
+			return nil
+		}
+		amount := asset.Quantity()
+
+		line, err := e.historicalBullionLine(ctx, v, metal, amount)
+		if err != nil {
+			line, err = e.lastKnown(ctx, v, ClassBullion, metal, amount, err)
+			if err != nil {
+				return fmt.Errorf("could not get price for %s: %w", metal, err)
+			}
+		}
+		e.addHolding(ctx, v, *line, asset)
+		return nil
+	})
+}
+
+func (e *Engine) historicalBullionLine(ctx context.Context, v *Valuation, metal string, amount float64) (*Line, error) {
+	if e.bullion == nil {
+		return nil, e.bullionErr
+	}
+
+	response, err := e.bullion.GetHistoricalPrice(ctx, metal, "USD", v.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := e.rate(ctx, v, "USD")
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := v.Timestamp
+	if response.Timestamp > 0 {
+		timestamp = time.Unix(response.Timestamp, 0)
+	}
+
+	return &Line{
+		Class:     ClassBullion,
+		Symbol:    metal,
+		Amount:    amount,
+		Price:     response.Price * rate,
+		Value:     amount * response.Price * rate,
+		Currency:  v.Currency,
+		Source:    "goldapi",
+		Timestamp: timestamp,
+	}, nil
+}
+
+func (e *Engine) valueTrading212At(ctx context.Context, p *pool, v *Valuation, account trading212Account) error {
+	line, err := e.lastKnown(ctx, v, ClassStocks, account.name, 1, errNoTrading212History)
+	if err != nil {
+		return err
+	}
+	v.Add(*line)
+	return nil
+}
@@ -0,0 +1,84 @@
+package valuation
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// KnownPrice is a price recorded by an earlier valuation
+type KnownPrice struct {
+	Price    float64
+	Currency string
+	AsOf     time.Time
+}
+
+// PriceHistory looks up the last price recorded for an asset on or before a
+// point in time; it returns nil without an error when there is none
+type PriceHistory interface {
+	LastPrice(ctx context.Context, class AssetClass, symbol string, before time.Time) (*KnownPrice, error)
+}
+
+// SetPriceHistory enables falling back to last known prices when a live source fails
+func (e *Engine) SetPriceHistory(history PriceHistory) {
+	e.history = history
+}
+
+// lastKnown builds a stale line from the last recorded price of an asset,
+// returning liveErr when there is no history to fall back to. The lookup is
+// not bound by the valuation deadline, which may be what failed the live price
+func (e *Engine) lastKnown(ctx context.Context, v *Valuation, class AssetClass, symbol string, amount float64, liveErr error) (*Line, error) {
+	if e.history == nil {
+		return nil, liveErr
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	known, err := e.history.LastPrice(ctx, class, symbol, v.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("%w (no fallback: %v)", liveErr, err)
+	}
+	if known == nil {
+		return nil, fmt.Errorf("%w (no price has ever been recorded)", liveErr)
+	}
+
+	rate, err := e.rate(ctx, v, known.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("%w (no fallback: %v)", liveErr, err)
+	}
+
+	source, _ := ctx.Value(sourceKey{}).(string)
+
+	return &Line{
+		Class:     class,
+		Symbol:    symbol,
+		Amount:    amount,
+		Price:     known.Price * rate,
+		Value:     amount * known.Price * rate,
+		Currency:  v.Currency,
+		Source:    "history",
+		Timestamp: known.AsOf,
+		Stale:     true,
+		staleFor:  source,
+	}, nil
+}
+
+// FormatAge renders how long ago a price was observed, e.g. "2 days ago"
+func FormatAge(t time.Time) string {
+	age := time.Since(t)
+	switch {
+	case age < time.Hour:
+		return plural(int(age.Minutes()), "minute") + " ago"
+	case age < 48*time.Hour:
+		return plural(int(age.Hours()), "hour") + " ago"
+	default:
+		return plural(int(age.Hours()/24), "day") + " ago"
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,56 @@
+package valuation
+
+import (
+	"context"
+	"fmt"
+	"investment-tracker/internal/portfolio"
+	"os"
+	"strings"
+	"time"
+)
+
+// Where the holdings are read from, set by HOLDINGS_SOURCE
+const (
+	HoldingsSourceFile    = "file"
+	HoldingsSourceMongoDB = "mongodb"
+)
+
+// HoldingsStore keeps the holdings and the history of their changes
+type HoldingsStore interface {
+	CurrentHoldings(ctx context.Context) (*portfolio.Holdings, error)
+	HoldingsAt(ctx context.Context, asOf time.Time) (*portfolio.Holdings, error)
+}
+
+// HoldingsSource returns HOLDINGS_SOURCE, defaulting to the holdings file
+func HoldingsSource() (string, error) {
+	source := strings.ToLower(strings.TrimSpace(os.Getenv("HOLDINGS_SOURCE")))
+	switch source {
+	case "":
+		return HoldingsSourceFile, nil
+	case HoldingsSourceFile, HoldingsSourceMongoDB:
+		return source, nil
+	}
+	return "", fmt.Errorf("invalid HOLDINGS_SOURCE %q, expected file or mongodb", source)
+}
+
+// SetHoldingsStore reads the holdings from a store instead of the holdings
+// file, so past valuations use the holdings as they stood on the day
+func (e *Engine) SetHoldingsStore(store HoldingsStore) {
+	e.holdingsStore = store
+}
+
+// holdings returns the current holdings, or those held at a past time
+func (e *Engine) holdings(ctx context.Context, asOf time.Time) (*portfolio.Holdings, error) {
+	if e.holdingsSource == HoldingsSourceFile {
+		return portfolio.LoadHoldings(e.holdingsPath)
+	}
+
+	// Falling back to the file would silently value an outdated portfolio
+	if e.holdingsStore == nil {
+		return nil, fmt.Errorf("HOLDINGS_SOURCE is %s but no database is connected", e.holdingsSource)
+	}
+	if asOf.IsZero() {
+		return e.holdingsStore.CurrentHoldings(ctx)
+	}
+	return e.holdingsStore.HoldingsAt(ctx, asOf)
+}
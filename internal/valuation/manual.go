@@ -0,0 +1,134 @@
+package valuation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"investment-tracker/internal/portfolio"
+)
+
+// errNoManualPriceHistory is returned because hand-kept equity prices only have a current value
+var errNoManualPriceHistory = errors.New("no price kept by hand for the day")
+
+// manualSources values the stocks and savings sections of the holdings file,
+// when they have entries. Savings accrue interest on the valuation day
+func (e *Engine) manualSources(h *portfolio.Holdings, equities func(ctx context.Context, p *pool, v *Valuation, h *portfolio.Holdings) error) []source {
+	var sources []source
+	if len(h.Stocks) > 0 {
+		sources = append(sources, source{ClassEquities, "manual", func(ctx context.Context, p *pool, v *Valuation) error {
+			return equities(ctx, p, v, h)
+		}})
+	}
+	if len(h.Savings) > 0 {
+		sources = append(sources, source{ClassSavings, "manual", func(ctx context.Context, p *pool, v *Valuation) error {
+			return e.valueSavings(ctx, v, h)
+		}})
+	}
+	return sources
+}
+
+// valueEquities values stocks held outside Trading212 at their hand-kept prices
+func (e *Engine) valueEquities(ctx context.Context, p *pool, v *Valuation, h *portfolio.Holdings) error {
+	var errs []error
+	for _, symbol := range sortedKeys(h.Stocks) {
+		equity := h.Stocks[symbol]
+
+		line, err := e.equityLine(ctx, v, symbol, equity, equity.Asset())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		e.addHolding(ctx, v, *line, equity.Asset())
+	}
+	return errors.Join(errs...)
+}
+
+// valueEquitiesAt values the equities held on a past day at their hand-kept
+// price if it was set by then, or else at the last price in a snapshot
+func (e *Engine) valueEquitiesAt(ctx context.Context, p *pool, v *Valuation, h *portfolio.Holdings) error {
+	var errs []error
+	for _, symbol := range sortedKeys(h.Stocks) {
+		equity := h.Stocks[symbol]
+		asset, held := equity.Asset().HeldOn(v.Timestamp)
+		if !held {
+			continue
+		}
+
+		var line *Line
+		var err error
+		if !equity.PriceDate.IsZero() && !equity.PriceDate.After(v.Timestamp) {
+			line, err = e.equityLine(ctx, v, symbol, equity, asset)
+		} else {
+			line, err = e.lastKnown(ctx, v, ClassEquities, symbol, asset.Quantity(), errNoManualPriceHistory)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not get price for %s: %w", symbol, err))
+			continue
+		}
+		e.addHolding(ctx, v, *line, asset)
+	}
+	return errors.Join(errs...)
+}
+
+func (e *Engine) equityLine(ctx context.Context, v *Valuation, symbol string, equity portfolio.Equity, asset portfolio.Asset) (*Line, error) {
+	rate, err := e.rate(ctx, v, equity.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert %s from %s: %w", symbol, equity.Currency, err)
+	}
+
+	timestamp := equity.PriceDate.Time
+	if timestamp.IsZero() {
+		timestamp = v.Timestamp
+	}
+
+	amount := asset.Quantity()
+	return &Line{
+		Class:     ClassEquities,
+		Symbol:    symbol,
+		Amount:    amount,
+		Price:     equity.Price * rate,
+		Value:     amount * equity.Price * rate,
+		Currency:  v.Currency,
+		Source:    "manual",
+		Timestamp: timestamp,
+	}, nil
+}
+
+// valueSavings values each savings account with the interest accrued by the
+// valuation day. The line's amount is in the account currency and its price
+// is the exchange rate; the balance paid in is its cost
+func (e *Engine) valueSavings(ctx context.Context, v *Valuation, h *portfolio.Holdings) error {
+	var errs []error
+	for _, name := range sortedKeys(h.Savings) {
+		account := h.Savings[name]
+		if !account.OpenOn(v.Timestamp) {
+			continue
+		}
+
+		amount, err := account.ValueOn(v.Timestamp)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not value %s: %w", name, err))
+			continue
+		}
+
+		rate, err := e.rate(ctx, v, account.Currency)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not convert %s from %s: %w", name, account.Currency, err))
+			continue
+		}
+
+		v.Add(Line{
+			Class:     ClassSavings,
+			Symbol:    name,
+			Amount:    amount,
+			Price:     rate,
+			Value:     amount * rate,
+			Currency:  v.Currency,
+			Source:    "manual",
+			Timestamp: v.Timestamp,
+			Cost:      account.Balance * rate,
+			HasCost:   true,
+		})
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,53 @@
+package valuation
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// pool bounds how many price requests a valuation has in flight at once
+type pool struct {
+	slots chan struct{}
+}
+
+func newPool(limit int) *pool {
+	if limit < 1 {
+		limit = 1
+	}
+	return &pool{
+		slots: make(chan struct{}, limit),
+	}
+}
+
+// do runs task once a slot is free, or gives up when ctx is done first
+func (p *pool) do(ctx context.Context, task func() error) error {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-p.slots }()
+
+	return task()
+}
+
+// each runs task for every key concurrently, within the pool limit, and
+// joins the errors in key order
+func (p *pool) each(ctx context.Context, keys []string, task func(key string) error) error {
+	errs := make([]error, len(keys))
+
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = p.do(ctx, func() error {
+				return task(key)
+			})
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
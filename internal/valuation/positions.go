@@ -0,0 +1,110 @@
+package valuation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"investment-tracker/internal/pricecache"
+	"investment-tracker/internal/stocks"
+	"log"
+	"time"
+)
+
+// addCash records how an account total splits into free cash, invested
+// money, the open result and pie cash; entries cached without it are skipped
+func (e *Engine) addCash(v *Valuation, account trading212Account, total pricecache.Entry, rate float64) {
+	var cash stocks.AccountCash
+	if len(total.Data) == 0 || json.Unmarshal(total.Data, &cash) != nil {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.Cash = append(v.Cash, Cash{
+		Account:  e.accountLabel(account),
+		Free:     cash.Free * rate,
+		Invested: cash.Invested * rate,
+		Result:   cash.Result * rate,
+		PieCash:  cash.PieCash * rate,
+	})
+}
+
+// addPositions records every open position of an account. Like pies, the
+// breakdown is informational and failing to get it leaves the total intact
+func (e *Engine) addPositions(ctx context.Context, p *pool, v *Valuation, account trading212Account, accountRate float64) {
+	positions, err := e.trading212Positions(ctx, p, account)
+	if err != nil {
+		log.Printf("Warning: Could not get %s positions: %v", account.name, err)
+		return
+	}
+
+	// Positions are priced in their instrument's currency
+	err = p.do(ctx, func() error {
+		return e.instruments.EnsureFresh(ctx, account.client)
+	})
+	if err != nil {
+		log.Printf("Warning: Could not get the Trading212 instrument catalogue: %v", err)
+		return
+	}
+
+	for _, position := range positions {
+		instrument, ok := e.instruments.Lookup(position.Ticker)
+		if !ok {
+			log.Printf("Warning: %s is not in the instrument catalogue, leaving it out of the position breakdown", position.Ticker)
+			continue
+		}
+
+		rate, err := e.rate(ctx, v, instrument.CurrencyCode)
+		if err != nil {
+			log.Printf("Warning: Could not convert %s position: %v", position.Ticker, err)
+			continue
+		}
+
+		v.mu.Lock()
+		v.Positions = append(v.Positions, Position{
+			Account:      e.accountLabel(account),
+			Ticker:       position.Ticker,
+			Name:         instrument.Name,
+			Type:         instrument.Type,
+			Currency:     instrument.CurrencyCode,
+			Quantity:     position.Quantity,
+			AveragePrice: position.AveragePrice * rate,
+			Price:        position.CurrentPrice * rate,
+			Value:        position.Quantity * position.CurrentPrice * rate,
+			PnL:          position.Ppl * accountRate,
+			FxPnL:        position.FxPpl * accountRate,
+		})
+		v.mu.Unlock()
+	}
+}
+
+// trading212Positions returns the open positions of an account, from the cache when fresh
+func (e *Engine) trading212Positions(ctx context.Context, p *pool, account trading212Account) ([]stocks.Position, error) {
+	key := "positions/" + account.name
+	if entry, ok := e.cache.Get("trading212", key, ""); ok {
+		var positions []stocks.Position
+		if err := json.Unmarshal(entry.Data, &positions); err == nil {
+			return positions, nil
+		}
+	}
+
+	if account.client == nil {
+		return nil, fmt.Errorf("failed to create Trading212 client: %w", e.t212Err)
+	}
+
+	var positions []stocks.Position
+	err := p.do(ctx, func() error {
+		var err error
+		positions, err = account.client.GetPortfolio(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(positions); err == nil {
+		e.store("trading212", key, "", pricecache.Entry{Timestamp: time.Now(), Data: data})
+	}
+
+	return positions, nil
+}
@@ -0,0 +1,50 @@
+package valuation
+
+import (
+	"context"
+	"investment-tracker/internal/conversion"
+	"investment-tracker/internal/portfolio"
+	"time"
+)
+
+// Realized converts a disposal from the ledger into a currency: its proceeds
+// at the rate of the day it happened and the cost of the lots it used at the
+// rates of the days they were acquired. Its PnL is the realised result
+func Realized(ctx context.Context, converter *conversion.Converter, currency string, r portfolio.Realization) (Performance, error) {
+	var realized Performance
+
+	if r.Proceeds != 0 {
+		proceeds, err := converter.Convert(ctx, r.Proceeds, r.Currency, currency, r.Time)
+		if err != nil {
+			return Performance{}, err
+		}
+		realized.Value = proceeds
+	}
+
+	cost, err := CostBasis(ctx, converter, currency, r.Lots, r.Time)
+	if err != nil {
+		return Performance{}, err
+	}
+	realized.Cost = cost
+
+	return realized, nil
+}
+
+// CostBasis converts the cost of lots into a currency at the rate of the day
+// each was acquired, or at asOf for lots without a date
+func CostBasis(ctx context.Context, converter *conversion.Converter, currency string, lots []portfolio.Lot, asOf time.Time) (float64, error) {
+	var total float64
+	for _, lot := range lots {
+		acquired := lot.Date.Time
+		if acquired.IsZero() {
+			acquired = asOf
+		}
+
+		cost, err := converter.Convert(ctx, lot.Cost(), lot.Currency, currency, acquired)
+		if err != nil {
+			return 0, err
+		}
+		total += cost
+	}
+	return total, nil
+}
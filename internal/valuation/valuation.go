@@ -0,0 +1,287 @@
+package valuation
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// AssetClass groups valuation lines for subtotals
+type AssetClass string
+
+const (
+	ClassStocks  AssetClass = "stocks"
+	ClassCrypto  AssetClass = "crypto"
+	ClassBullion AssetClass = "bullion"
+
+	// ClassEquities and ClassSavings are the stocks and savings accounts
+	// tracked by hand in the holdings file
+	ClassEquities AssetClass = "equities"
+	ClassSavings  AssetClass = "savings"
+)
+
+// Status describes the outcome of fetching a price source
+type Status string
+
+const (
+	StatusOK     Status = "ok"
+	StatusStale  Status = "stale"
+	StatusFailed Status = "failed"
+)
+
+// SourceResult records how a price source fared during a valuation
+type SourceResult struct {
+	Source string
+	Class  AssetClass
+	Status Status
+	Error  string
+	AsOf   time.Time
+}
+
+// Line represents the value of a single asset in the valuation currency
+type Line struct {
+	Class     AssetClass
+	Symbol    string
+	Amount    float64
+	Price     float64
+	Value     float64
+	Currency  string
+	Source    string
+	Timestamp time.Time
+
+	// Stale is set when the live source failed and Price is the last known price,
+	// observed at Timestamp
+	Stale bool
+
+	// staleFor names the failed source a stale line stands in for
+	staleFor string
+
+	// Cost is what the holding cost in the valuation currency, set when
+	// HasCost; holdings given as plain amounts have no cost basis
+	Cost    float64
+	HasCost bool
+}
+
+// Pie is the value of one Trading212 pie in the valuation currency
+type Pie struct {
+	// Account names the Trading212 account holding the pie, when several are configured
+	Account string
+
+	ID       int64
+	Name     string
+	Invested float64
+	Value    float64
+	Result   float64
+	Cash     float64
+
+	// Progress towards the pie's goal, from 0 to 1
+	Progress float64
+}
+
+// Position is one Trading212 holding, priced in the valuation currency at the current rate
+type Position struct {
+	Account string
+	Ticker  string
+
+	// Name, Type and Currency describe the instrument, e.g. "Apple", STOCK and USD
+	Name     string
+	Type     string
+	Currency string
+
+	Quantity     float64
+	AveragePrice float64
+	Price        float64
+	Value        float64
+
+	// PnL is the unrealised result, of which FxPnL came from currency moves
+	PnL   float64
+	FxPnL float64
+}
+
+// Cash splits a Trading212 account value, in the valuation currency
+type Cash struct {
+	Account  string
+	Free     float64
+	Invested float64
+	Result   float64
+	PieCash  float64
+}
+
+// Valuation represents a point-in-time valuation of the whole portfolio
+type Valuation struct {
+	Currency  string
+	Timestamp time.Time
+	Lines     []Line
+	Subtotals map[AssetClass]float64
+	Total     float64
+	Sources   []SourceResult
+
+	// Rates holds the exchange rates used, keyed as "USD/BGN"
+	Rates map[string]float64
+
+	// Pies breaks the Trading212 value down per pie; money outside pies is not listed
+	Pies []Pie
+
+	// Positions and Cash break each live Trading212 account down further
+	Positions []Position
+	Cash      []Cash
+
+	// Reconstructed is set for valuations of a past day rebuilt from historical prices
+	Reconstructed bool
+
+	// mu guards Lines, the totals, Rates and the Trading212 breakdowns while sources are fetched concurrently
+	mu sync.Mutex
+}
+
+func New(currency string) *Valuation {
+	return &Valuation{
+		Currency:  currency,
+		Timestamp: time.Now(),
+		Subtotals: make(map[AssetClass]float64),
+		Rates:     make(map[string]float64),
+	}
+}
+
+// Add appends a line and updates the class subtotal and the total
+func (v *Valuation) Add(line Line) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.Lines = append(v.Lines, line)
+	v.Subtotals[line.Class] += line.Value
+	v.Total += line.Value
+}
+
+// classOrder is the order in which lines are listed
+var classOrder = map[AssetClass]int{
+	ClassStocks:   0,
+	ClassEquities: 1,
+	ClassCrypto:   2,
+	ClassBullion:  3,
+	ClassSavings:  4,
+}
+
+// sortLines orders lines by class and symbol, and the Trading212 breakdowns
+// by account, since concurrent fetches add them in any order
+func (v *Valuation) sortLines() {
+	sort.SliceStable(v.Pies, func(i, j int) bool {
+		return v.Pies[i].Account < v.Pies[j].Account
+	})
+	sort.SliceStable(v.Positions, func(i, j int) bool {
+		a, b := v.Positions[i], v.Positions[j]
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		return a.Ticker < b.Ticker
+	})
+	sort.SliceStable(v.Cash, func(i, j int) bool {
+		return v.Cash[i].Account < v.Cash[j].Account
+	})
+
+	sort.SliceStable(v.Lines, func(i, j int) bool {
+		a, b := v.Lines[i], v.Lines[j]
+		if a.Class != b.Class {
+			return classOrder[a.Class] < classOrder[b.Class]
+		}
+		return a.Symbol < b.Symbol
+	})
+}
+
+// Subtotal returns the summed value of all lines in a class
+func (v *Valuation) Subtotal(class AssetClass) float64 {
+	return v.Subtotals[class]
+}
+
+// LinesFor returns the lines that belong to a class
+func (v *Valuation) LinesFor(class AssetClass) []Line {
+	var lines []Line
+	for _, line := range v.Lines {
+		if line.Class == class {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Record stores the outcome of a price source. A nil error means the source is ok,
+// unless some of its own lines had to fall back to last known prices
+func (v *Valuation) Record(class AssetClass, source string, err error) {
+	result := SourceResult{
+		Source: source,
+		Class:  class,
+		Status: StatusOK,
+		AsOf:   time.Now(),
+	}
+
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	} else {
+		for _, line := range v.Stale() {
+			if line.Class != class || line.staleFor != source {
+				continue
+			}
+			result.Status = StatusStale
+			if line.Timestamp.Before(result.AsOf) {
+				result.AsOf = line.Timestamp
+			}
+		}
+	}
+
+	v.Sources = append(v.Sources, result)
+}
+
+// Stale returns the lines valued with last known instead of live prices
+func (v *Valuation) Stale() []Line {
+	var lines []Line
+	for _, line := range v.Lines {
+		if line.Stale {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// ClassStatus returns the worst status among the sources of a class
+func (v *Valuation) ClassStatus(class AssetClass) Status {
+	status := StatusOK
+	for _, source := range v.Sources {
+		if source.Class != class {
+			continue
+		}
+		if source.Status == StatusFailed {
+			return StatusFailed
+		}
+		if source.Status == StatusStale {
+			status = StatusStale
+		}
+	}
+	return status
+}
+
+// Failed returns the sources that could not be fetched
+func (v *Valuation) Failed() []SourceResult {
+	var failed []SourceResult
+	for _, source := range v.Sources {
+		if source.Status == StatusFailed {
+			failed = append(failed, source)
+		}
+	}
+	return failed
+}
+
+// StaleSources returns the sources that fell back to last known prices
+func (v *Valuation) StaleSources() []SourceResult {
+	var stale []SourceResult
+	for _, source := range v.Sources {
+		if source.Status == StatusStale {
+			stale = append(stale, source)
+		}
+	}
+	return stale
+}
+
+// Complete reports whether every source was fetched successfully
+func (v *Valuation) Complete() bool {
+	return len(v.Failed()) == 0
+}
@@ -0,0 +1,45 @@
+package valuation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStaleLineOnlyMarksItsOwnSource(t *testing.T) {
+	e := testEngine(t, "EUR", knownPrices{
+		"stocks/isa": {Price: 900, Currency: "EUR", AsOf: time.Now().Add(-24 * time.Hour)},
+	})
+
+	live := source{ClassStocks, "trading212/invest", func(ctx context.Context, p *pool, v *Valuation) error {
+		v.Add(Line{Class: ClassStocks, Symbol: "invest", Amount: 1, Price: 1000, Value: 1000, Currency: "EUR", Source: "trading212/invest", Timestamp: time.Now()})
+		return nil
+	}}
+	stale := source{ClassStocks, "trading212/isa", func(ctx context.Context, p *pool, v *Valuation) error {
+		line, err := e.lastKnown(ctx, v, ClassStocks, "isa", 1, errors.New("HTTP 503"))
+		if err != nil {
+			return err
+		}
+		v.Add(*line)
+		return nil
+	}}
+
+	v, err := e.collect(context.Background(), New("EUR"), live, stale)
+	if err != nil {
+		t.Fatalf("valuation failed: %v", err)
+	}
+
+	want := map[string]Status{
+		"trading212/invest": StatusOK,
+		"trading212/isa":    StatusStale,
+	}
+	for _, result := range v.Sources {
+		if result.Status != want[result.Source] {
+			t.Errorf("%s status = %s, want %s", result.Source, result.Status, want[result.Source])
+		}
+	}
+	if got := v.ClassStatus(ClassStocks); got != StatusStale {
+		t.Errorf("stocks status = %s, want stale", got)
+	}
+}
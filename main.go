@@ -1,70 +1,100 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"investment-tracker/internal/conversion"
-	"investment-tracker/internal/portfolio"
-	"investment-tracker/internal/stocks"
+	"investment-tracker/internal/database"
+	"investment-tracker/internal/valuation"
 	"log"
+
+	"github.com/joho/godotenv"
 )
 
 func main() {
-	var h *portfolio.Holdings
-
-	h, err := portfolio.LoadHoldings("config/holdings.json")
-	if err != nil {
-		log.Fatal(err)
+	// Load environment variables from .env file (optional)
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Info: No .env file found, using environment variables: %v", err)
 	}
 
-	sum, err := portfolio.SumHoldingsTotal(h)
+	noCache := flag.Bool("no-cache", false, "ignore cached prices and fetch everything live")
+	flag.Parse()
+
+	engine, err := valuation.NewEngine(valuation.DefaultHoldingsPath)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	// Get Trading212 stocks value (in BGN)
-	stocksValueBGN, err := getTrading212Value()
-	if err != nil {
-		log.Printf("Warning: Could not get Trading212 stocks value: %v", err)
-		stocksValueBGN = 0
+	if *noCache {
+		engine.ForceRefresh()
 	}
 
-	// Convert crypto/bullion from USD to BGN
-	sumBGN := conversion.USDToBGN(sum, 1.7346)
-	
-	// Add Trading212 BGN value directly (no conversion needed)
-	totalBGN := sumBGN + stocksValueBGN
-
-	fmt.Printf("\n Trading212 Stocks Value: %.2f BGN", stocksValueBGN)
-	fmt.Printf("\n Total Investment Worth: %.2f BGN", totalBGN)
-
-}
+	// The tracker otherwise runs without a database
+	if source, _ := valuation.HoldingsSource(); source == valuation.HoldingsSourceMongoDB {
+		db, err := database.NewMongoDB()
+		if err != nil {
+			log.Fatal("Failed to connect to database for holdings:", err)
+		}
+		defer db.Close()
+		engine.SetHoldingsStore(database.NewHoldingsService(db))
+	}
 
-func getTrading212Value() (float64, error) {
-	client, err := stocks.NewClientFromConfig()
+	v, err := engine.Value(context.Background())
 	if err != nil {
-		return 0, fmt.Errorf("failed to create Trading212 client: %w", err)
+		log.Fatal(err)
 	}
 
-	// Get account cash - this contains all the value we need
-	cash, err := client.GetAccountCash()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get account cash: %w", err)
+	for _, line := range v.Lines {
+		fmt.Printf("\n %-8s %-10s %12.6f x %12.2f = %12.2f %s (%s)",
+			line.Class, line.Symbol, line.Amount, line.Price, line.Value, line.Currency, line.Source)
+		if line.Stale {
+			fmt.Printf(" stale, price from %s", valuation.FormatAge(line.Timestamp))
+		}
+		if performance, ok := line.Performance(); ok {
+			fmt.Printf(" cost %.2f, P&L %+.2f (%+.2f%%)", performance.Cost, performance.PnL(), performance.PnLPercent())
+		}
 	}
-	
-	fmt.Printf("\n=== Trading212 Account Debug ===\n")
-	fmt.Printf("Free Cash (available to invest): %.2f %s\n", cash.Free, cash.CurrencyCode)
-	fmt.Printf("Invested Amount (in stocks): %.2f %s\n", cash.Invested, cash.CurrencyCode)
-	fmt.Printf("Total Cash (free + invested): %.2f %s\n", cash.Total, cash.CurrencyCode)
 
-	// We only need the total cash - it includes both free cash and invested amount
-	finalValue := cash.Total
-	
-	fmt.Printf("Using Total Cash as Trading212 value: %.2f %s\n", finalValue, cash.CurrencyCode)
-	fmt.Printf("=== End Debug ===\n")
+	for _, source := range v.Failed() {
+		fmt.Printf("\n ⚠️ %s (%s) failed: %s", source.Source, source.Class, source.Error)
+	}
 
-	if cash.CurrencyCode != "BGN" {
-		log.Printf("Warning: Trading212 account currency is %s, expected BGN", cash.CurrencyCode)
+	fmt.Printf("\n\n Trading212 Stocks Value: %.2f %s", v.Subtotal(valuation.ClassStocks), v.Currency)
+	if accounts := v.LinesFor(valuation.ClassStocks); len(accounts) > 1 {
+		for _, account := range accounts {
+			fmt.Printf("\n   %-20s %12.2f %s", account.Symbol, account.Value, v.Currency)
+		}
+	}
+	for _, pie := range v.Pies {
+		name := pie.Name
+		if pie.Account != "" {
+			name = pie.Account + "/" + pie.Name
+		}
+		fmt.Printf("\n   %-20s %12.2f %s (result %+.2f)", name, pie.Value, v.Currency, pie.Result)
 	}
+	for _, position := range v.Positions {
+		name := position.Name
+		if position.Account != "" {
+			name = position.Account + "/" + name
+		}
+		fmt.Printf("\n   %-20s %-5s %-4s %12.4f x %10.2f = %12.2f %s (P&L %+.2f)",
+			name, position.Type, position.Currency, position.Quantity, position.Price, position.Value, v.Currency, position.PnL)
+	}
+	fmt.Printf("\n Crypto Value: %.2f %s%s", v.Subtotal(valuation.ClassCrypto), v.Currency, classPnL(v, valuation.ClassCrypto))
+	fmt.Printf("\n Bullion Value: %.2f %s%s", v.Subtotal(valuation.ClassBullion), v.Currency, classPnL(v, valuation.ClassBullion))
+	if len(v.LinesFor(valuation.ClassEquities)) > 0 {
+		fmt.Printf("\n Stocks Value: %.2f %s%s", v.Subtotal(valuation.ClassEquities), v.Currency, classPnL(v, valuation.ClassEquities))
+	}
+	if len(v.LinesFor(valuation.ClassSavings)) > 0 {
+		fmt.Printf("\n Savings Value: %.2f %s%s", v.Subtotal(valuation.ClassSavings), v.Currency, classPnL(v, valuation.ClassSavings))
+	}
+	fmt.Printf("\n Total Investment Worth: %.2f %s\n", v.Total, v.Currency)
+}
 
-	return finalValue, nil
+// classPnL describes the result of the holdings in a class that have a cost basis
+func classPnL(v *valuation.Valuation, class valuation.AssetClass) string {
+	performance, ok := v.ClassPerformance(class)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(" (cost %.2f, P&L %+.2f, %+.2f%%)", performance.Cost, performance.PnL(), performance.PnLPercent())
 }
@@ -29,7 +29,7 @@ fi
 
 # Test the notification system (dry run)
 echo "🔔 Testing notification system..."
-if go run cmd/notify.go test 2>/dev/null; then
+if go run ./cmd/notify test 2>/dev/null; then
     echo "✅ Notification system test passed"
 else
     echo "⚠️  Notification system test failed (expected if API keys not set)"
@@ -38,7 +38,7 @@ fi
 
 # Test the main tracking command
 echo "📊 Testing portfolio tracking..."
-if go run cmd/notify.go now 2>/dev/null; then
+if go run ./cmd/notify now 2>/dev/null; then
     echo "✅ Portfolio tracking test passed"
 else
     echo "⚠️  Portfolio tracking test failed (expected if API keys not set)"